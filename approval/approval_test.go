@@ -0,0 +1,121 @@
+package approval_test
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/approval"
+	"perun.network/perun-nervos-demo/client"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+func TestRules(t *testing.T) {
+	prop := newProposal(t, 500, 400)
+	peer, err := address.AsParticipant(prop.Peer).ToCKBAddress(types.NetworkTest).Encode()
+	require.NoError(t, err)
+
+	rules := loadRules(t, "max_ckbytes: \"400\"\npeers: ["+peer+"]\n")
+	require.True(t, rules.Approver(types.NetworkTest, nil)(context.Background(), prop).Accept)
+
+	d := loadRules(t, "max_ckbytes: \"399.99\"\n").Approver(types.NetworkTest, nil)(context.Background(), prop)
+	require.False(t, d.Accept)
+	require.Equal(t, "Own CKBytes contribution 400 exceeds the limit of 399.99", d.Reason)
+
+	other := newProposal(t, 500, 400)
+	d = rules.Approver(types.NetworkTest, nil)(context.Background(), other)
+	require.False(t, d.Accept)
+	require.Contains(t, d.Reason, "not accepted")
+
+	_, err = approval.LoadRules(writeFile(t, "max_ckbytes: \"1.123456789\"\n"))
+	require.Error(t, err)
+}
+
+func TestSUDTRules(t *testing.T) {
+	// The SUDT has 2 decimals, so that 150 units are 1.5 SUDT.
+	prop, sudt := newSUDTProposal(t, 150)
+	decimals := client.Decimals{sudt.SUDT.TypeScript.Hash(): 2}
+
+	require.True(t, loadRules(t, "max_sudt: \"1.5\"\n").Approver(types.NetworkTest, decimals)(context.Background(), prop).Accept)
+	d := loadRules(t, "max_sudt: \"1.49\"\n").Approver(types.NetworkTest, decimals)(context.Background(), prop)
+	require.False(t, d.Accept)
+	require.Equal(t, "Own SUDT contribution 1.5 exceeds the limit of 1.49", d.Reason)
+	d = loadRules(t, "max_sudt: \"1.499\"\n").Approver(types.NetworkTest, decimals)(context.Background(), prop)
+	require.False(t, d.Accept)
+
+	// Without its decimals, the SUDT is counted in whole units.
+	require.False(t, loadRules(t, "max_sudt: \"100\"\n").Approver(types.NetworkTest, nil)(context.Background(), prop).Accept)
+
+	in, answers := io.Pipe()
+	var out strings.Builder
+	go answers.Write([]byte("yes\n"))
+	require.True(t, approval.Prompt(in, &out, types.NetworkTest, decimals)(context.Background(), prop).Accept)
+	require.Contains(t, out.String(), "SUDT: peer 0, own 1.5")
+}
+
+func TestPrompt(t *testing.T) {
+	prop := newProposal(t, 500, 400)
+	in, answers := io.Pipe()
+	var out strings.Builder
+	approve := approval.Prompt(in, &out, types.NetworkTest, nil)
+
+	go answers.Write([]byte("yes\n"))
+	require.True(t, approve(context.Background(), prop).Accept)
+	require.Contains(t, out.String(), "CKBytes: peer 500, own 400")
+
+	go answers.Write([]byte("too much\n"))
+	require.Equal(t, wallet_service.Reject("too much"), approve(context.Background(), prop))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.False(t, approve(ctx, prop).Accept)
+}
+
+func newProposal(t *testing.T, peerBal, ownBal int64) *wallet_service.Proposal {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	peer, err := address.NewDefaultParticipant(key.PubKey())
+	require.NoError(t, err)
+	ckb := asset.NewCKBytesAsset()
+	alloc := channel.NewAllocation(2, ckb)
+	alloc.SetAssetBalances(ckb, []channel.Bal{
+		new(big.Int).Mul(big.NewInt(peerBal), big.NewInt(100_000_000)),
+		new(big.Int).Mul(big.NewInt(ownBal), big.NewInt(100_000_000)),
+	})
+	return &wallet_service.Proposal{Peer: peer, Allocation: alloc, ChallengeDuration: 60}
+}
+
+// newSUDTProposal creates a proposal of a channel in which we contribute own
+// units of a SUDT.
+func newSUDTProposal(t *testing.T, own int64) (*wallet_service.Proposal, *asset.Asset) {
+	prop := newProposal(t, 500, 400)
+	script := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData, Args: []byte{1}}
+	sudt := asset.NewSUDTAsset(asset.NewSUDT(script, 14400000000))
+	alloc := channel.NewAllocation(2, sudt)
+	alloc.SetAssetBalances(sudt, []channel.Bal{big.NewInt(0), big.NewInt(own)})
+	prop.Allocation = alloc
+	return prop, sudt
+}
+
+func loadRules(t *testing.T, rules string) *approval.Rules {
+	r, err := approval.LoadRules(writeFile(t, rules))
+	require.NoError(t, err)
+	return r
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
@@ -0,0 +1,90 @@
+package approval
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/client"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+// Prompt returns an approver which asks the user on the command line. It
+// writes the proposal to out and reads the answer from in: "y" or "yes"
+// accepts the proposal, an empty answer, "n" or "no" rejects it, and any other
+// answer rejects it with the answer as reason. Amounts are shown with the
+// given decimals.
+func Prompt(in io.Reader, out io.Writer, network types.Network, decimals client.Decimals) wallet_service.Approver {
+	// A single reader goroutine is shared by all prompts, as reads from in
+	// cannot be cancelled.
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(lines)
+	}()
+
+	return func(ctx context.Context, prop *wallet_service.Proposal) wallet_service.Decision {
+		// Discard answers which were given while no proposal was pending.
+		for pending := true; pending; {
+			select {
+			case _, ok := <-lines:
+				pending = ok
+			default:
+				pending = false
+			}
+		}
+		fmt.Fprint(out, describe(prop, network, decimals))
+		fmt.Fprint(out, "Accept? [y/N or a reason to reject]: ")
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return wallet_service.Reject("No answer")
+			}
+			switch strings.ToLower(line) {
+			case "y", "yes":
+				return wallet_service.Accept()
+			case "", "n", "no":
+				return wallet_service.Reject("Rejected by the user")
+			default:
+				return wallet_service.Reject(line)
+			}
+		case <-ctx.Done():
+			fmt.Fprintln(out, "\nTimed out.")
+			return wallet_service.Reject("Proposal was not approved in time")
+		}
+	}
+}
+
+// describe describes the proposal for the user.
+func describe(prop *wallet_service.Proposal, network types.Network, decimals client.Decimals) string {
+	peer, err := address.AsParticipant(prop.Peer).ToCKBAddress(network).Encode()
+	if err != nil {
+		peer = prop.Peer.String()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Channel proposal from %s\n", peer)
+	for _, a := range prop.Allocation.Assets {
+		name := "SUDT"
+		if ckbAsset, ok := a.(*asset.Asset); ok && ckbAsset.IsCKBytes {
+			name = "CKBytes"
+		}
+		d, err := decimals.Of(a)
+		if err != nil {
+			fmt.Fprintf(&b, "  unknown asset: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(&b, "  %s: peer %s, own %s\n", name,
+			client.NewAmount(prop.Allocation.Balance(0, a), d),
+			client.NewAmount(prop.Allocation.Balance(1, a), d))
+	}
+	fmt.Fprintf(&b, "  Challenge duration: %ds\n", prop.ChallengeDuration)
+	return b.String()
+}
@@ -0,0 +1,126 @@
+// Package approval provides approvers of incoming channel proposals for the
+// wallet service.
+package approval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"gopkg.in/yaml.v3"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/client"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+// Rules decide on channel proposals without asking the user. They are read
+// from a YAML file like
+//
+//	# CKB addresses of the peers whose proposals are accepted. Proposals of
+//	# all peers are accepted if the list is empty.
+//	peers:
+//	  - ckt1qzda0cr08m85hc8jlnfp3zer7xulejywt49kt2rr0vthywaa50xwsqd...
+//	# Our highest contribution to a channel in CKBytes and in each SUDT.
+//	max_ckbytes: "1000"
+//	max_sudt: "100"
+//
+// Limits which are not set do not restrict the proposals.
+type Rules struct {
+	Peers      []string `yaml:"peers"`
+	MaxCKBytes string   `yaml:"max_ckbytes"`
+	MaxSUDT    string   `yaml:"max_sudt"`
+
+	maxCKBytes *client.Amount
+}
+
+// LoadRules reads the rules from the file at path.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading approval rules: %w", err)
+	}
+	var r Rules
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parsing approval rules: %w", err)
+	}
+	if r.maxCKBytes, err = parseLimit(r.MaxCKBytes, client.CKBytesDecimals); err != nil {
+		return nil, fmt.Errorf("max_ckbytes: %w", err)
+	}
+	// The limit of each SUDT is parsed with its decimals when a proposal
+	// arrives, so only its syntax is checked here.
+	if _, err = parseLimit(r.MaxSUDT, math.MaxUint8); err != nil {
+		return nil, fmt.Errorf("max_sudt: %w", err)
+	}
+	return &r, nil
+}
+
+func parseLimit(s string, decimals uint8) (*client.Amount, error) {
+	if s == "" {
+		return nil, nil
+	}
+	a, err := client.ParseAmount(s, decimals)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Approver returns an approver which decides by the rules. Peer addresses are
+// encoded for the given network and SUDT limits are read with the decimals of
+// the respective SUDT.
+func (r *Rules) Approver(network types.Network, decimals client.Decimals) wallet_service.Approver {
+	return func(_ context.Context, prop *wallet_service.Proposal) wallet_service.Decision {
+		return r.decide(prop, network, decimals)
+	}
+}
+
+func (r *Rules) decide(prop *wallet_service.Proposal, network types.Network, decimals client.Decimals) wallet_service.Decision {
+	if len(r.Peers) > 0 {
+		peer, err := address.AsParticipant(prop.Peer).ToCKBAddress(network).Encode()
+		if err != nil {
+			return wallet_service.Reject(fmt.Sprintf("Invalid peer address: %v", err))
+		}
+		if !contains(r.Peers, peer) {
+			return wallet_service.Reject("Proposals of this peer are not accepted")
+		}
+	}
+	for _, a := range prop.Allocation.Assets {
+		ckbAsset, ok := a.(*asset.Asset)
+		if !ok {
+			return wallet_service.Reject(fmt.Sprintf("Unsupported asset type %T", a))
+		}
+		limit, name := r.maxCKBytes, "CKBytes"
+		if !ckbAsset.IsCKBytes {
+			name = "SUDT"
+			d, err := decimals.Of(a)
+			if err != nil {
+				return wallet_service.Reject(fmt.Sprintf("Unsupported asset: %v", err))
+			}
+			if limit, err = parseLimit(r.MaxSUDT, d); err != nil {
+				return wallet_service.Reject(fmt.Sprintf("SUDT limit %s does not fit its %d decimals", r.MaxSUDT, d))
+			}
+		}
+		if limit == nil {
+			continue
+		}
+		// We are the participant with index 1.
+		own := prop.Allocation.Balance(1, a)
+		if own.Cmp(limit.Units()) > 0 {
+			return wallet_service.Reject(fmt.Sprintf("Own %s contribution %s exceeds the limit of %s",
+				name, client.NewAmount(own, limit.Decimals()), limit))
+		}
+	}
+	return wallet_service.Accept()
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"perun.network/perun-nervos-demo/audit"
+	"perun.network/perun-nervos-demo/config"
+)
+
+const auditUsage = `Usage: perun-nervos-demo audit [flags]
+
+Verifies the hash chain of a wallet service's audit log and prints a summary
+of the entries in the given time range as JSON to stdout. Times are given in
+RFC 3339 format, e.g. 2024-06-01T12:00:00Z.
+
+Flags:
+`
+
+// runAuditCommand runs the audit command given by args and returns the exit
+// code of the process.
+func runAuditCommand(cfg *config.Config, args []string) int {
+	var name, path, from, to string
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, auditUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&name, "as", cfg.Participants[0].Name, "name of the participant whose audit log is verified")
+	fs.StringVar(&path, "file", "", "path of the audit log (default: the participant's audit_log)")
+	fs.StringVar(&from, "from", "", "start of the summarised time range")
+	fs.StringVar(&to, "to", "", "end of the summarised time range")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if path == "" {
+		i, err := participantIndex(cfg, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		if path = cfg.Participants[i].AuditLog; path == "" {
+			fmt.Fprintf(os.Stderr, "%v: %s has no audit log\n", errUsage, cfg.Participants[i].Name)
+			return exitUsage
+		}
+	}
+	fromTime, err := parseTime(from)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: -from: %v\n", errUsage, err)
+		return exitUsage
+	}
+	toTime, err := parseTime(to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: -to: %v\n", errUsage, err)
+		return exitUsage
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	defer file.Close()
+	summary, err := audit.Verify(file, fromTime, toTime)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(summary)
+	return exitOK
+}
+
+// parseTime parses an RFC 3339 time, which is zero if s is empty.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,222 @@
+// Package audit implements the tamper-evident audit log of a wallet service.
+// The log is a file of JSON lines, one per entry, which is only ever appended
+// to. Every entry contains the hash of its predecessor, so that changing,
+// removing or reordering entries breaks the chain, which Verify detects.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Type is the kind of request recorded by an entry.
+type Type string
+
+const (
+	// TypeProposal is the decision on an incoming channel proposal.
+	TypeProposal Type = "proposal"
+	// TypeSignState is a request to sign a channel state.
+	TypeSignState Type = "sign_state"
+	// TypeSignTransaction is a request to sign a transaction.
+	TypeSignTransaction Type = "sign_transaction"
+	// TypeUpdate is the notification about a channel update.
+	TypeUpdate Type = "update"
+)
+
+// ErrBroken is returned if the hash chain of a log is broken.
+var ErrBroken = errors.New("audit log is broken")
+
+// Entry is one entry of the audit log.
+type Entry struct {
+	Seq     uint64    `json:"seq"`  // Set by Append.
+	Time    time.Time `json:"time"` // Set by Append if zero.
+	Type    Type      `json:"type"`
+	Channel string    `json:"channel,omitempty"` // Hex encoded channel ID.
+	Version uint64    `json:"version"`
+	// Payload is the hex encoded hash of what was signed: the blake2b hash of
+	// a channel state's encoding or the hash of a transaction.
+	Payload  string `json:"payload,omitempty"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"` // Why the request was rejected.
+	Prev     string `json:"prev"`             // Hash of the previous entry, set by Append.
+}
+
+// record is one line of the log. The hash covers the entry exactly as it is
+// stored, which includes the hash of the previous entry.
+type record struct {
+	Entry json.RawMessage `json:"entry"`
+	Hash  string          `json:"hash"`
+}
+
+// genesis is the predecessor hash of the first entry.
+var genesis = hex.EncodeToString(make([]byte, sha256.Size))
+
+// Log is an audit log which is open for appending.
+type Log struct {
+	mtx  sync.Mutex
+	file *os.File
+	seq  uint64
+	prev string
+}
+
+// Open opens the audit log at path, which is created if it does not exist.
+// An existing log is verified first, so that new entries are never chained
+// to a broken log.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{file: file, prev: genesis}
+	err = scan(file, func(e *Entry, hash string) {
+		l.seq, l.prev = e.Seq+1, hash
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading audit log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Append chains the entry to the log and writes it to disk.
+func (l *Log) Append(e Entry) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	e.Seq, e.Prev = l.seq, l.prev
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	hash := hashEntry(data)
+	line, err := json.Marshal(record{Entry: data, Hash: hash})
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("syncing audit log: %w", err)
+	}
+	l.seq, l.prev = l.seq+1, hash
+	return nil
+}
+
+// Close closes the log.
+func (l *Log) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.file.Close()
+}
+
+// Count counts the accepted and rejected requests of one type.
+type Count struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+// ChannelSummary summarises the channel states which were signed.
+type ChannelSummary struct {
+	SignedStates  int    `json:"signed_states"`
+	LatestVersion uint64 `json:"latest_version"`
+}
+
+// Summary summarises the entries of a log in a time range.
+type Summary struct {
+	Entries  int                        `json:"entries"`  // All verified entries of the log.
+	InRange  int                        `json:"in_range"` // Entries in the time range.
+	Types    map[Type]*Count            `json:"types"`
+	Channels map[string]*ChannelSummary `json:"channels"`
+}
+
+// Verify checks the hash chain of the log read from r and summarises its
+// entries from the time from until the time to. A zero time leaves the range
+// open at that end. If the chain is broken, the error wraps ErrBroken.
+func Verify(r io.Reader, from, to time.Time) (*Summary, error) {
+	sum := &Summary{
+		Types:    make(map[Type]*Count),
+		Channels: make(map[string]*ChannelSummary),
+	}
+	err := scan(r, func(e *Entry, _ string) {
+		sum.Entries++
+		if !from.IsZero() && e.Time.Before(from) || !to.IsZero() && e.Time.After(to) {
+			return
+		}
+		sum.InRange++
+		c, ok := sum.Types[e.Type]
+		if !ok {
+			c = new(Count)
+			sum.Types[e.Type] = c
+		}
+		if !e.Accepted {
+			c.Rejected++
+			return
+		}
+		c.Accepted++
+		if e.Type != TypeSignState || e.Channel == "" {
+			return
+		}
+		ch, ok := sum.Channels[e.Channel]
+		if !ok {
+			ch = new(ChannelSummary)
+			sum.Channels[e.Channel] = ch
+		}
+		ch.SignedStates++
+		if e.Version > ch.LatestVersion {
+			ch.LatestVersion = e.Version
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+// scan verifies the entries read from r and passes them to fn in order,
+// together with their hash.
+func scan(r io.Reader, fn func(e *Entry, hash string)) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	seq, prev := uint64(0), genesis
+	for line := 1; s.Scan(); line++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrBroken, line, err)
+		}
+		if hashEntry(rec.Entry) != rec.Hash {
+			return fmt.Errorf("%w: line %d: hash mismatch", ErrBroken, line)
+		}
+		var e Entry
+		if err := json.Unmarshal(rec.Entry, &e); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrBroken, line, err)
+		}
+		if e.Seq != seq || e.Prev != prev {
+			return fmt.Errorf("%w: line %d: found entry %d instead of %d", ErrBroken, line, e.Seq, seq)
+		}
+		fn(&e, rec.Hash)
+		seq, prev = seq+1, rec.Hash
+	}
+	return s.Err()
+}
+
+// hashEntry returns the hex encoded hash of an encoded entry.
+func hashEntry(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,61 @@
+package audit_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/perun-nervos-demo/audit"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{Time: start, Type: audit.TypeProposal, Channel: "aa", Accepted: true},
+		{Time: start.Add(time.Minute), Type: audit.TypeSignState, Channel: "aa", Version: 0, Payload: "01", Accepted: true},
+		{Time: start.Add(2 * time.Minute), Type: audit.TypeSignState, Channel: "aa", Version: 1, Payload: "02", Accepted: true},
+		{Time: start.Add(3 * time.Minute), Type: audit.TypeSignTransaction, Payload: "03", Reason: "wrong lock"},
+	}
+	l, err := audit.Open(path)
+	require.NoError(t, err)
+	for _, e := range entries[:2] {
+		require.NoError(t, l.Append(e))
+	}
+	require.NoError(t, l.Close())
+	// Reopened logs continue the chain.
+	l, err = audit.Open(path)
+	require.NoError(t, err)
+	for _, e := range entries[2:] {
+		require.NoError(t, l.Append(e))
+	}
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	sum, err := audit.Verify(bytes.NewReader(data), start.Add(time.Minute), time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 4, sum.Entries)
+	require.Equal(t, 3, sum.InRange)
+	require.Equal(t, audit.Count{Accepted: 2}, *sum.Types[audit.TypeSignState])
+	require.Equal(t, audit.Count{Rejected: 1}, *sum.Types[audit.TypeSignTransaction])
+	require.Nil(t, sum.Types[audit.TypeProposal])
+	require.Equal(t, audit.ChannelSummary{SignedStates: 2, LatestVersion: 1}, *sum.Channels["aa"])
+
+	// Changing or removing an entry breaks the chain.
+	tampered := bytes.Replace(data, []byte(`"version":1`), []byte(`"version":2`), 1)
+	_, err = audit.Verify(bytes.NewReader(tampered), time.Time{}, time.Time{})
+	require.ErrorIs(t, err, audit.ErrBroken)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	removed := bytes.Join(append(lines[:1:1], lines[2:]...), nil)
+	_, err = audit.Verify(bytes.NewReader(removed), time.Time{}, time.Time{})
+	require.ErrorIs(t, err, audit.ErrBroken)
+
+	// New entries are not chained to a broken log.
+	require.NoError(t, os.WriteFile(path, tampered, 0600))
+	_, err = audit.Open(path)
+	require.ErrorIs(t, err, audit.ErrBroken)
+}
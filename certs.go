@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/mtls"
+)
+
+const certsUsage = `Usage: perun-nervos-demo certs [flags]
+
+Generates a local CA and the certificates for mutual TLS between the demo
+clients, the channel service, the wallet services and the watchtower if it is
+configured: one server certificate for each service, and a channel service and
+client certificate for every participant. The CA and existing certificates are kept, so the command can be
+run again to issue certificates for new accounts. The written files are
+printed as JSON to stdout.
+
+Flags:
+`
+
+// runCertsCommand runs the certs command given by args and returns the exit
+// code of the process.
+func runCertsCommand(cfg *config.Config, args []string) int {
+	var dir, names string
+	if cfg.TLS != nil {
+		dir = cfg.TLS.CertDir
+	}
+	fs := flag.NewFlagSet("certs", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, certsUsage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&dir, "dir", dir, "directory of the certificates (default: the configured tls cert_dir)")
+	fs.StringVar(&names, "names", "", "comma separated names of further wallet accounts")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if dir == "" {
+		fmt.Fprintf(os.Stderr, "%v: -dir is required without tls cert_dir\n", errUsage)
+		return exitUsage
+	}
+
+	servers := []string{mtls.WalletService, mtls.ChannelService}
+	if cfg.Watchtower != nil {
+		servers = append(servers, mtls.Watchtower)
+	}
+	var clients []string
+	for _, p := range cfg.Participants {
+		clients = append(clients, mtls.ChannelServiceOf(p.Name), mtls.ClientOf(p.Name))
+	}
+	// Wallet accounts have no demo client, only a channel service acting for
+	// them.
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			clients = append(clients, mtls.ChannelServiceOf(name))
+		}
+	}
+	written, err := mtls.Generate(dir, servers, clients)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(struct {
+		Dir     string   `json:"dir"`
+		Written []string `json:"written"`
+	}{dir, written})
+	return exitOK
+}
+
+// channelServiceCredentials returns the credentials with which the client of
+// the named participant connects to its channel service, or nil if mutual TLS
+// is not configured.
+func channelServiceCredentials(cfg *config.Config, name string) (credentials.TransportCredentials, error) {
+	if cfg.TLS == nil {
+		return nil, nil
+	}
+	creds, err := mtls.ClientCredentials(mtls.DirFiles(cfg.TLS.CertDir, mtls.ClientOf(name)), mtls.ChannelService)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s's client certificate: %w", name, err)
+	}
+	return creds, nil
+}
+
+// walletServiceCredentials returns the credentials with which the client of
+// the named participant subscribes to the events of its wallet service, or nil
+// if mutual TLS is not configured.
+func walletServiceCredentials(cfg *config.Config, name string) (credentials.TransportCredentials, error) {
+	if cfg.TLS == nil {
+		return nil, nil
+	}
+	creds, err := mtls.ClientCredentials(mtls.DirFiles(cfg.TLS.CertDir, mtls.ClientOf(name)), mtls.WalletService)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s's client certificate: %w", name, err)
+	}
+	return creds, nil
+}
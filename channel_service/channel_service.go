@@ -1,34 +1,40 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/rpc"
 	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"perun.network/channel-service/rpc/proto"
 	"perun.network/channel-service/service"
 	"perun.network/channel-service/wallet"
 	"perun.network/perun-ckb-backend/backend"
 	"perun.network/perun-ckb-backend/wallet/address"
 	"perun.network/perun-ckb-backend/wallet/external"
+	"perun.network/perun-nervos-demo/config"
 	"perun.network/perun-nervos-demo/deployment"
+	"perun.network/perun-nervos-demo/dispute"
+	"perun.network/perun-nervos-demo/keystore"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/readiness"
 	"polycry.pt/poly-go/sortedkv/leveldb"
 )
 
 const (
-	rpcNodeURL  = "http://localhost:8114"
-	network     = types.NetworkTest
-	hostA       = "localhost:4321"
-	hostB       = "localhost:4322"
-	aliceWSSURL = "localhost:50051"
-	bobWSSURL   = "localhost:50052"
+	// configPath is the default location of the demo configuration.
+	configPath = "../config.yaml"
 )
 
 // SetLogFile sets the log file for the channel service.
@@ -40,25 +46,13 @@ func SetLogFile(path string) {
 	log.SetOutput(logFile)
 }
 
-func parseSUDTOwnerLockArg(pathToSUDTOwnerLockArg string) (string, error) {
-	b, err := os.ReadFile(pathToSUDTOwnerLockArg)
-	if err != nil {
-		return "", fmt.Errorf("reading sudt owner lock arg from file: %w", err)
-	}
-	sudtOwnerLockArg := string(b)
-	if sudtOwnerLockArg == "" {
-		return "", errors.New("sudt owner lock arg not found in file")
-	}
-	return sudtOwnerLockArg, nil
-}
-
 // MakeDeployment creates a deployment object.
-func MakeDeployment() (backend.Deployment, error) {
-	sudtOwnerLockArg, err := parseSUDTOwnerLockArg("../devnet/accounts/sudt-owner-lock-hash.txt")
+func MakeDeployment(cfg *config.Config) (backend.Deployment, error) {
+	sudtOwnerLockArg, err := deployment.GetSUDTOwnerLockArg(cfg.SUDTOwnerLockArgFile())
 	if err != nil {
-		log.Fatalf("error getting SUDT owner lock arg: %v", err)
+		return backend.Deployment{}, fmt.Errorf("getting SUDT owner lock arg: %w", err)
 	}
-	d, _, err := deployment.GetDeployment("../devnet/contracts/migrations/dev/", "../devnet/system_scripts", sudtOwnerLockArg)
+	d, _, err := deployment.GetDeployment(cfg.MigrationDir(), cfg.SystemScriptsDir(), sudtOwnerLockArg)
 	return d, err
 }
 
@@ -79,127 +73,137 @@ func MakeParticipants(pks []secp256k1.PublicKey) ([]address.Participant, error)
 func main() {
 	SetLogFile("channel_service.log")
 
-	// Set up ChannelService
-	d, err := MakeDeployment()
+	cfg, err := config.Load(configPath)
 	if err != nil {
-		log.Fatalf("error getting deployment: %v", err)
+		log.Fatalf("error loading config: %v", err)
 	}
-
-	keyAlice, err := deployment.GetKey("../devnet/accounts/alice.pk")
+	network, err := cfg.CKBNetwork()
 	if err != nil {
-		log.Fatalf("error getting alice's private key: %v", err)
+		log.Fatalf("error getting network: %v", err)
 	}
-	keyBob, err := deployment.GetKey("../devnet/accounts/bob.pk")
+
+	// Set up ChannelService
+	d, err := MakeDeployment(cfg)
 	if err != nil {
-		log.Fatalf("error getting bob's private key: %v", err)
+		log.Fatalf("error getting deployment: %v", err)
 	}
 
-	pubKeys := make([]secp256k1.PublicKey, 2)
-	pubKeys[0] = *keyAlice.PubKey()
-	pubKeys[1] = *keyBob.PubKey()
+	pubKeys := make([]secp256k1.PublicKey, len(cfg.Participants))
+	for i, cp := range cfg.Participants {
+		// Only the public keys are needed, so keystores stay locked.
+		pub, err := keystore.ReadPublicKey(cp.KeyFile, cp.KeyFormat)
+		if err != nil {
+			log.Fatalf("error getting %s's public key: %v", cp.Name, err)
+		}
+		pubKeys[i] = *pub
+	}
 
 	parts, err := MakeParticipants(pubKeys)
 	if err != nil {
 		log.Fatalf("error making participants: %v", err)
 	}
+	log.Printf("Participants: %v", parts)
 
-	aliceWSC := setupWalletServiceClient(aliceWSSURL)
-	bobWSC := setupWalletServiceClient(bobWSSURL)
+	// Every participant gets its own channel service with its own database,
+	// served at the participant's channel service address.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	servers := make([]*grpc.Server, len(cfg.Participants))
+	for i, cp := range cfg.Participants {
+		servers[i] = setupUser(ctx, cfg, cp, parts[i], network, d)
+	}
 
-	// Setup Alice
-	dbAlice, err := leveldb.LoadDatabase("./alice-db")
-	if err != nil {
-		log.Fatalf("loading database: %v", err)
+	// Signal handling for graceful shutdown
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+	fmt.Println("Shutting down gRPC servers...")
+
+	// Graceful stop
+	for _, s := range servers {
+		s.Stop()
 	}
 
-	csA, err := service.NewChannelService(aliceWSC, network, rpcNodeURL, d, nil, dbAlice)
-	if err != nil {
-		log.Fatalf("creating channel service: %v", err)
+	fmt.Println("gRPC servers stopped.")
+}
+
+// setupUser creates the channel service of the given participant, starts
+// serving it and initializes the participant as its user once its wallet
+// service is ready. If a watchtower is configured, the user's channel states
+// are forwarded to it. With mutual TLS, only the participant's client is served.
+// The channel service is ready while the node is reachable, its indexer is in
+// sync and the user is initialized.
+func setupUser(ctx context.Context, cfg *config.Config, cp config.Participant, part address.Participant, network types.Network, d backend.Deployment) *grpc.Server {
+	var opts []grpc.ServerOption
+	var wsCreds credentials.TransportCredentials
+	if cfg.TLS != nil {
+		creds, err := mtls.ServerCredentials(mtls.DirFiles(cfg.TLS.CertDir, mtls.ChannelService))
+		if err != nil {
+			log.Fatalf("loading channel service certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+		opts = append(opts, mtls.RequireIdentity(mtls.ClientOf(cp.Name))...)
+		wsCreds, err = mtls.ClientCredentials(mtls.DirFiles(cfg.TLS.CertDir, mtls.ChannelServiceOf(cp.Name)), mtls.WalletService)
+		if err != nil {
+			log.Fatalf("loading %s's channel service certificate: %v", cp.Name, err)
+		}
 	}
+	wsc, wsConn := setupWalletServiceClient(cp.WalletService, &part, wsCreds)
 
-	// Setup Bob
-	dbBob, err := leveldb.LoadDatabase("./bob-db")
+	db, err := leveldb.LoadDatabase(cp.DBDir)
 	if err != nil {
-		log.Fatalf("loading database: %v", err)
+		log.Fatalf("loading %s's database: %v", cp.Name, err)
+	}
+	if cfg.Watchtower != nil {
+		go forwardStates(cp.Name, db, setupWatchtowerClient(cfg, cp.Name))
 	}
 
-	csB, err := service.NewChannelService(bobWSC, network, rpcNodeURL, d, nil, dbBob)
+	cs, err := service.NewChannelService(wsc, network, cfg.NodeURL, d, nil, db)
 	if err != nil {
-		log.Fatalf("creating channel service: %v", err)
+		log.Fatalf("creating %s's channel service: %v", cp.Name, err)
 	}
-
-	lisA, err := net.Listen("tcp", hostA)
+	chain, err := rpc.Dial(cfg.NodeURL)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatalf("dialing node: %v", err)
 	}
 
-	lisB, err := net.Listen("tcp", hostB)
+	lis, err := net.Listen("tcp", cp.ChannelService)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	var opts []grpc.ServerOption
-	sA := grpc.NewServer(opts...)
-	proto.RegisterChannelServiceServer(sA, csA)
-
-	sB := grpc.NewServer(opts...)
-	proto.RegisterChannelServiceServer(sB, csB)
-
-	// Signal handling for graceful shutdown
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
-	// Channel to notify when servers are stopped
-	done := make(chan bool, 1)
+	s := grpc.NewServer(opts...)
+	channels := new(sync.Mutex) // Guards the channels of the user.
+	proto.RegisterChannelServiceServer(s, rejectionServer{lockedServer{cs, channels}})
+	dispute.RegisterServer(s, &disputeServer{cs: cs, channels: channels})
+	health := readiness.NewServer(s)
 
-	// Handle termination signal in a separate goroutine
 	go func() {
-		<-sigs
-		fmt.Println("Shutting down gRPC servers...")
-
-		// Graceful stop
-		sA.Stop()
-		sB.Stop()
-
-		fmt.Println("gRPC servers stopped.")
-		done <- true
-	}()
-
-	// Start the servers
-	go func() {
-		fmt.Printf("Starting Alice Channel Service Server at %s \n", hostA)
-		err = sA.Serve(lisA)
-		if err != nil {
+		fmt.Printf("Starting %s Channel Service Server at %s \n", cp.Name, cp.ChannelService)
+		if err := s.Serve(lis); err != nil {
 			log.Fatalf("serving channel service: %v", err)
 		}
 	}()
 
+	var initialized atomic.Bool
 	go func() {
-		fmt.Printf("Starting Bob Channel Service Server at %s \n", hostB)
-		err = sB.Serve(lisB)
+		waitCtx, cancel := context.WithTimeout(ctx, cfg.ReadyWait())
+		defer cancel()
+		if err := readiness.WaitReady(waitCtx, wsConn); err != nil {
+			log.Fatalf("waiting for %s's wallet service: %v", cp.Name, err)
+		}
+		_, err := cs.InitializeUser(part, wsc, external.NewWallet(wallet.NewExternalClient(wsc)))
 		if err != nil {
-			log.Fatalf("serving channel service: %v", err)
+			log.Fatalf("error initializing user %s: %v", cp.Name, err)
 		}
+		initialized.Store(true)
 	}()
-
-	log.Printf("Participants: %v", parts)
-	// Initialize Users
-	for i, part := range parts {
-		if i == 0 {
-			_, err = csA.InitializeUser(part, aliceWSC, external.NewWallet(wallet.NewExternalClient(aliceWSC)))
-			if err != nil {
-				log.Fatalf("error initializing user A: %v", err)
-			}
-		} else {
-			_, err = csB.InitializeUser(part, bobWSC, external.NewWallet(wallet.NewExternalClient(bobWSC)))
-			if err != nil {
-				log.Fatalf("error initializing user B: %v", err)
-			}
-		}
-		if err != nil {
-			log.Fatalf("error initializing user: %v", err)
+	userInitialized := func(context.Context) error {
+		if !initialized.Load() {
+			return errors.New("user not initialized")
 		}
+		return nil
 	}
-
-	// Wait for the servers to stop
-	<-done
+	go readiness.Monitor(ctx, health, cp.Name+"'s channel service", readiness.DefaultInterval,
+		readiness.NodeCheck(chain), readiness.IndexerCheck(chain), userInitialized)
+	return s
 }
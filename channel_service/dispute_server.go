@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/channel-service/service"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-nervos-demo/dispute"
+)
+
+// phasePollInterval is the interval in which the phase of a channel is checked
+// while it is force-closed.
+const phasePollInterval = 500 * time.Millisecond
+
+// disputeServer implements the dispute service for the user of a channel
+// service.
+type disputeServer struct {
+	cs       *service.ChannelService
+	channels *sync.Mutex // Guards the channels of the user, see lockedServer.
+}
+
+var _ dispute.Server = (*disputeServer)(nil)
+
+// ForceClose registers the latest state of the channel on-chain, waits for the
+// challenge period to elapse and withdraws the funds. Unlike the cooperative
+// close of the channel service, this does not involve the peer.
+func (d *disputeServer) ForceClose(ctx context.Context, req *proto.ChannelCloseRequest, report func(channel.Phase) error) error {
+	cid, user, err := d.cs.GetChannelInfoFromRequest(req.GetChannelId())
+	if err != nil {
+		return fmt.Errorf("force close: %w", err)
+	}
+	d.channels.Lock()
+	ch, ok := user.Channels[cid]
+	d.channels.Unlock()
+	if !ok {
+		return fmt.Errorf("force close: %w", service.ErrChannelNotFound)
+	}
+
+	// The dispute has to be completed even if the requester goes away, so it
+	// only ends with the channel and cleans up after itself.
+	done := make(chan error, 1)
+	go func() {
+		err := ch.Settle(ch.Ctx(), false)
+		if err != nil {
+			log.Printf("Force-closing channel %x: %v", cid, err)
+		} else {
+			// Close frees up the channel resources like in a cooperative close.
+			_ = ch.Close()
+			d.channels.Lock()
+			delete(user.Channels, cid)
+			d.channels.Unlock()
+			log.Printf("Force-closed channel %x", cid)
+		}
+		done <- err
+	}()
+
+	log.Printf("Force-closing channel %x", cid)
+	phase := ch.Phase()
+	if err := report(phase); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(phasePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("force close: %w", err)
+			}
+			return report(channel.Withdrawn)
+		case <-ticker.C:
+			if p := ch.Phase(); p != phase {
+				phase = p
+				if err := report(phase); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"perun.network/channel-service/rpc/proto"
+)
+
+// lockedServer wraps the channel service of a user such that its requests
+// access the channels of the user one after another. The user does not guard
+// its channels itself, but the dispute server removes force-closed channels
+// concurrently to the requests of the channel service. Both hold mtx while
+// they access the channels. Channels of proposals which the peer sends are
+// still added by the user without it.
+type lockedServer struct {
+	proto.ChannelServiceServer
+	mtx *sync.Mutex
+}
+
+// OpenChannel forwards the request to the channel service.
+func (l lockedServer) OpenChannel(ctx context.Context, req *proto.ChannelOpenRequest) (*proto.ChannelOpenResponse, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ChannelServiceServer.OpenChannel(ctx, req)
+}
+
+// UpdateChannel forwards the request to the channel service.
+func (l lockedServer) UpdateChannel(ctx context.Context, req *proto.ChannelUpdateRequest) (*proto.ChannelUpdateResponse, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ChannelServiceServer.UpdateChannel(ctx, req)
+}
+
+// CloseChannel forwards the request to the channel service.
+func (l lockedServer) CloseChannel(ctx context.Context, req *proto.ChannelCloseRequest) (*proto.ChannelCloseResponse, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ChannelServiceServer.CloseChannel(ctx, req)
+}
+
+// GetChannels forwards the request to the channel service.
+func (l lockedServer) GetChannels(ctx context.Context, req *proto.GetChannelsRequest) (*proto.GetChannelsResponse, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ChannelServiceServer.GetChannels(ctx, req)
+}
+
+// RestoreChannels forwards the request to the channel service.
+func (l lockedServer) RestoreChannels(ctx context.Context, req *proto.RestoreChannelsRequest) (*proto.RestoreChannelsResponse, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.ChannelServiceServer.RestoreChannels(ctx, req)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+
+	"perun.network/channel-service/rpc/proto"
+)
+
+// rejectionServer wraps the channel service such that rejected requests are
+// answered with their Rejected response only. The channel service returns the
+// rejection reason also as error, which gRPC would send as plain Unknown
+// status instead of the response, so that clients could not tell a rejection
+// from any other failure of the service.
+type rejectionServer struct {
+	proto.ChannelServiceServer
+}
+
+// OpenChannel forwards the request to the channel service.
+func (r rejectionServer) OpenChannel(ctx context.Context, req *proto.ChannelOpenRequest) (*proto.ChannelOpenResponse, error) {
+	resp, err := r.ChannelServiceServer.OpenChannel(ctx, req)
+	if resp.GetRejected() != nil {
+		return resp, nil
+	}
+	return resp, err
+}
+
+// UpdateChannel forwards the request to the channel service.
+func (r rejectionServer) UpdateChannel(ctx context.Context, req *proto.ChannelUpdateRequest) (*proto.ChannelUpdateResponse, error) {
+	resp, err := r.ChannelServiceServer.UpdateChannel(ctx, req)
+	if resp.GetRejected() != nil {
+		return resp, nil
+	}
+	return resp, err
+}
+
+// CloseChannel forwards the request to the channel service.
+func (r rejectionServer) CloseChannel(ctx context.Context, req *proto.ChannelCloseRequest) (*proto.ChannelCloseResponse, error) {
+	resp, err := r.ChannelServiceServer.CloseChannel(ctx, req)
+	if resp.GetRejected() != nil {
+		return resp, nil
+	}
+	return resp, err
+}
@@ -6,17 +6,32 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"perun.network/channel-service/rpc/proto"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/wallet_service"
 )
 
-func setupWalletServiceClient(url string) proto.WalletServiceClient {
-	conn, err := grpc.Dial(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// setupWalletServiceClient connects to the wallet service at url, which may
+// host other accounts than the participant's as well. The connection is
+// secured with creds, or unencrypted if creds is nil. The connection is
+// returned as well to wait for the readiness of the wallet service.
+func setupWalletServiceClient(url string, part *address.Participant, creds credentials.TransportCredentials) (proto.WalletServiceClient, *grpc.ClientConn) {
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUnaryInterceptor(wallet_service.AccountInterceptor(part)),
+	}
+	conn, err := grpc.Dial(url, opts...)
 	if err != nil {
 		log.Fatalf("failed to dial: %v", err)
 	}
 
 	client := proto.NewWalletServiceClient(conn)
+	ready := conn
 
 	// Create a goroutine to monitor the connection and redial if necessary
 	go func() {
@@ -29,7 +44,7 @@ func setupWalletServiceClient(url string) proto.WalletServiceClient {
 						break
 					}
 					time.Sleep(1 * time.Second) // Adjust the retry interval as needed
-					conn, err = grpc.Dial(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+					conn, err = grpc.Dial(url, opts...)
 					if err != nil {
 						log.Printf("Error reconnecting: %v\n", err)
 					} else {
@@ -41,5 +56,5 @@ func setupWalletServiceClient(url string) proto.WalletServiceClient {
 		}
 	}()
 
-	return client
+	return client, ready
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/channel/persistence/keyvalue"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/watchtower"
+	"polycry.pt/poly-go/sortedkv"
+)
+
+// forwardInterval is the interval in which new channel states are forwarded to
+// the watchtower.
+const forwardInterval = time.Second
+
+// setupWatchtowerClient connects to the configured watchtower on behalf of the
+// participant with the given name. With mutual TLS, the channel service
+// presents the participant's certificate, as towards its wallet service.
+func setupWatchtowerClient(cfg *config.Config, name string) *watchtower.Client {
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		var err error
+		creds, err = mtls.ClientCredentials(mtls.DirFiles(cfg.TLS.CertDir, mtls.ChannelServiceOf(name)), mtls.Watchtower)
+		if err != nil {
+			log.Fatalf("loading %s's channel service certificate: %v", name, err)
+		}
+	}
+	conn, err := grpc.Dial(cfg.Watchtower.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("failed to dial watchtower: %v", err)
+	}
+	return watchtower.NewClient(conn)
+}
+
+// forwardStates sends the latest fully signed state of every channel of the
+// user to the watchtower whenever it changes. The wallet service only ever
+// sees the states but never the peer's signatures, so the states are read
+// from the user's channel database, where go-perun persists them with all
+// signatures.
+func forwardStates(name string, db sortedkv.Database, wt *watchtower.Client) {
+	pr := keyvalue.NewPersistRestorer(db)
+	forwarded := make(map[channel.ID]uint64)
+	ticker := time.NewTicker(forwardInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), forwardInterval)
+		it, err := pr.RestoreAll()
+		if err != nil {
+			log.Printf("reading %s's channels: %v", name, err)
+			cancel()
+			continue
+		}
+		for it.Next(ctx) {
+			ch := it.Channel()
+			tx := ch.CurrentTX()
+			if tx.State == nil || !fullySigned(tx.Sigs) {
+				continue
+			}
+			if v, ok := forwarded[ch.ID()]; ok && v >= tx.Version {
+				continue
+			}
+			signed := &channel.SignedState{Params: ch.Params(), State: tx.State, Sigs: tx.Sigs}
+			if err := wt.Watch(ctx, signed); err != nil {
+				log.Printf("forwarding %s's channel %x to the watchtower: %v", name, ch.ID(), err)
+				continue
+			}
+			forwarded[ch.ID()] = tx.Version
+		}
+		if err := it.Close(); err != nil {
+			log.Printf("reading %s's channels: %v", name, err)
+		}
+		cancel()
+	}
+}
+
+func fullySigned(sigs []gpwallet.Sig) bool {
+	for _, sig := range sigs {
+		if sig == nil {
+			return false
+		}
+	}
+	return len(sigs) > 0
+}
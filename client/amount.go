@@ -0,0 +1,175 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	gpchannel "perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/channel/asset"
+)
+
+const (
+	// CKBytesDecimals is the number of decimals of CKBytes, i.e., one CKByte
+	// is 10^8 Shannon.
+	CKBytesDecimals = 8
+	// SUDTDecimals is the number of decimals of the SUDTs whose decimals are
+	// not known, which are only transferred in whole units.
+	SUDTDecimals = 0
+)
+
+// Decimals maps SUDTs, which are identified by the hash of their type script,
+// to their number of decimals. SUDTs which it does not contain have
+// SUDTDecimals. The decimals of a SUDT are not recorded on-chain, so they are
+// configured like its name.
+type Decimals map[types.Hash]uint8
+
+// Amount is an exact, non-negative decimal amount of an asset. It is stored as
+// an integer number of the asset's smallest units together with the number of
+// decimals of the asset, e.g., 1.5 CKBytes are 150000000 units with 8
+// decimals.
+type Amount struct {
+	units    *big.Int
+	decimals uint8
+}
+
+// NewAmount creates an amount of the given number of smallest units of an
+// asset with the given number of decimals.
+func NewAmount(units *big.Int, decimals uint8) Amount {
+	return Amount{units: new(big.Int).Set(units), decimals: decimals}
+}
+
+// ParseAmount parses a decimal amount like "400" or "0.1" of an asset with the
+// given number of decimals. It fails if the amount is negative or more precise
+// than the asset allows.
+func ParseAmount(s string, decimals uint8) (Amount, error) {
+	intPart, fracPart, hasFrac := strings.Cut(strings.TrimSpace(s), ".")
+	if intPart == "" && fracPart == "" {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) || (hasFrac && fracPart == "") {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(fracPart) > int(decimals) {
+		return Amount{}, fmt.Errorf("amount %q has more than %d decimals", s, decimals)
+	}
+	digits := intPart + fracPart + strings.Repeat("0", int(decimals)-len(fracPart))
+	units, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return Amount{units: units, decimals: decimals}, nil
+}
+
+// Parse parses a decimal amount of the given asset.
+func (d Decimals) Parse(a gpchannel.Asset, s string) (Amount, error) {
+	decimals, err := d.Of(a)
+	if err != nil {
+		return Amount{}, err
+	}
+	return ParseAmount(s, decimals)
+}
+
+// Of returns the number of decimals of the given asset.
+func (d Decimals) Of(a gpchannel.Asset) (uint8, error) {
+	ckbAsset, ok := a.(*asset.Asset)
+	if !ok {
+		return 0, fmt.Errorf("asset is not of type *asset.Asset: %T", a)
+	}
+	if ckbAsset.IsCKBytes {
+		return CKBytesDecimals, nil
+	}
+	if ckbAsset.SUDT == nil {
+		return 0, errors.New("asset is neither CKBytes nor a SUDT")
+	}
+	if decimals, ok := d[ckbAsset.SUDT.TypeScript.Hash()]; ok {
+		return decimals, nil
+	}
+	return SUDTDecimals, nil
+}
+
+// common returns the number of decimals which all SUDTs in d have, or
+// SUDTDecimals if they differ. The on-chain SUDT balance of the client sums up
+// all SUDTs, so that it is only exact if they share their decimals, like the
+// single SUDT of the demo.
+func (d Decimals) common() uint8 {
+	common, first := uint8(SUDTDecimals), true
+	for _, decimals := range d {
+		if !first && decimals != common {
+			return SUDTDecimals
+		}
+		common, first = decimals, false
+	}
+	return common
+}
+
+// units returns the amount in smallest units of the given asset. It fails if
+// the amount was not given with the decimals of the asset.
+func (d Decimals) units(a gpchannel.Asset, amount Amount) (*big.Int, error) {
+	decimals, err := d.Of(a)
+	if err != nil {
+		return nil, err
+	}
+	if amount.decimals != decimals {
+		return nil, fmt.Errorf("amount %s has %d decimals, but the asset has %d", amount, amount.decimals, decimals)
+	}
+	return amount.Units(), nil
+}
+
+// amountFromFloat converts an amount entered as float64 to an exact Amount.
+// The float is interpreted as the shortest decimal which represents it, so
+// that, e.g., 0.1 becomes exactly 0.1 and not the closest binary float.
+func amountFromFloat(f float64, decimals uint8) (Amount, error) {
+	if f < 0 {
+		return Amount{}, errors.New("negative amount")
+	}
+	return ParseAmount(strconv.FormatFloat(f, 'f', -1, 64), decimals)
+}
+
+// Units returns the amount as integer number of the asset's smallest units.
+func (a Amount) Units() *big.Int {
+	if a.units == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.units)
+}
+
+// Decimals returns the number of decimals of the amount's asset.
+func (a Amount) Decimals() uint8 {
+	return a.decimals
+}
+
+// IsZero returns whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a.units == nil || a.units.Sign() == 0
+}
+
+// String returns the exact decimal representation of the amount without
+// trailing zeros in the fractional part, e.g., "400" or "0.1".
+func (a Amount) String() string {
+	digits := a.Units().String()
+	if a.decimals == 0 {
+		return digits
+	}
+	d := int(a.decimals)
+	if len(digits) <= d {
+		digits = strings.Repeat("0", d-len(digits)+1) + digits
+	}
+	intPart, fracPart := digits[:len(digits)-d], strings.TrimRight(digits[len(digits)-d:], "0")
+	if fracPart == "" {
+		return intPart
+	}
+	return intPart + "." + fracPart
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-nervos-demo/client"
+)
+
+func TestParseAmount(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		decimals uint8
+		units    int64
+		str      string
+	}{
+		{"400", client.CKBytesDecimals, 40_000_000_000, "400"},
+		{"0.1", client.CKBytesDecimals, 10_000_000, "0.1"},
+		{".5", client.CKBytesDecimals, 50_000_000, "0.5"},
+		{"1.23456789", client.CKBytesDecimals, 123_456_789, "1.23456789"},
+		{"0.00000001", client.CKBytesDecimals, 1, "0.00000001"},
+		{"20.50", client.CKBytesDecimals, 2_050_000_000, "20.5"},
+		{"0", client.CKBytesDecimals, 0, "0"},
+		{"42", client.SUDTDecimals, 42, "42"},
+	} {
+		a, err := client.ParseAmount(tc.in, tc.decimals)
+		require.NoError(t, err, tc.in)
+		require.Equal(t, big.NewInt(tc.units), a.Units(), tc.in)
+		require.Equal(t, tc.str, a.String(), tc.in)
+	}
+
+	for _, tc := range []struct {
+		in       string
+		decimals uint8
+	}{
+		{"", client.CKBytesDecimals},
+		{".", client.CKBytesDecimals},
+		{"1.", client.CKBytesDecimals},
+		{"-1", client.CKBytesDecimals},
+		{"1e3", client.CKBytesDecimals},
+		{"1.000000001", client.CKBytesDecimals},
+		{"0.5", client.SUDTDecimals},
+	} {
+		_, err := client.ParseAmount(tc.in, tc.decimals)
+		require.Error(t, err, tc.in)
+	}
+}
+
+func TestDecimals(t *testing.T) {
+	sudt := func(args byte) *asset.Asset {
+		script := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData, Args: []byte{args}}
+		return asset.NewSUDTAsset(asset.NewSUDT(script, 14400000000))
+	}
+	cents, whole := sudt(1), sudt(2)
+	decimals := client.Decimals{cents.SUDT.TypeScript.Hash(): 2}
+
+	ckb, err := decimals.Parse(asset.NewCKBytesAsset(), "0.3")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(30_000_000), ckb.Units())
+
+	// SUDTs have their own decimals, or none if they are not known.
+	amount, err := decimals.Parse(cents, "1.25")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(125), amount.Units())
+	require.Equal(t, "1.25", amount.String())
+	_, err = decimals.Parse(cents, "0.125")
+	require.Error(t, err)
+	_, err = decimals.Parse(whole, "0.3")
+	require.Error(t, err)
+	amount, err = decimals.Parse(whole, "3")
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(3), amount.Units())
+
+	_, err = decimals.Parse(&asset.Asset{}, "1")
+	require.Error(t, err)
+}
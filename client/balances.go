@@ -7,7 +7,6 @@ import (
 	"log"
 	"math"
 	"math/big"
-	"strconv"
 	"time"
 
 	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
@@ -31,28 +30,16 @@ func sudtBalanceExtractor(cell *indexer.LiveCell) *big.Int {
 func (p *WalletClient) PollBalances() {
 	defer log.Println("PollBalances: stopped")
 	pollingInterval := time.Second
-	searchKey := &indexer.SearchKey{
-		Script:           address.AsParticipant(p.Account.Address()).PaymentScript,
-		ScriptType:       types.ScriptTypeLock,
-		ScriptSearchMode: types.ScriptSearchModeExact,
-		Filter:           nil,
-		WithData:         true,
-	}
 	log.Println("PollBalances")
 	updateBalance := func() {
-		ctx, _ := context.WithTimeout(context.Background(), pollingInterval)
+		ctx, cancel := context.WithTimeout(context.Background(), pollingInterval)
+		defer cancel()
 
-		cells, err := p.rpcClient.GetCells(ctx, searchKey, indexer.SearchOrderDesc, math.MaxUint32, "")
+		ckbBalance, sudtBalance, err := p.FetchBalances(ctx)
 		if err != nil {
 			log.Println("balance poll error: ", err)
 			return
 		}
-		ckbBalance := big.NewInt(0)
-		sudtBalance := big.NewInt(0)
-		for _, cell := range cells.Objects {
-			ckbBalance = new(big.Int).Add(ckbBalance, ckbBalanceExtractor(cell))
-			sudtBalance = new(big.Int).Add(sudtBalance, sudtBalanceExtractor(cell))
-		}
 
 		p.balanceMutex.Lock()
 		if ckbBalance.Cmp(p.balance) != 0 || sudtBalance.Cmp(p.sudtBalance) != 0 {
@@ -76,10 +63,35 @@ func (p *WalletClient) PollBalances() {
 	}
 }
 
-func FormatBalance(ckbBal, sudtBal *big.Int) string {
+// FetchBalances queries the indexer for the on-chain CKBytes and SUDT
+// balances of the client.
+func (p *WalletClient) FetchBalances(ctx context.Context) (ckbBalance, sudtBalance *big.Int, err error) {
+	searchKey := &indexer.SearchKey{
+		Script:           address.AsParticipant(p.WalletAddress()).PaymentScript,
+		ScriptType:       types.ScriptTypeLock,
+		ScriptSearchMode: types.ScriptSearchModeExact,
+		Filter:           nil,
+		WithData:         true,
+	}
+	cells, err := p.rpcClient.GetCells(ctx, searchKey, indexer.SearchOrderDesc, math.MaxUint32, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	ckbBalance = big.NewInt(0)
+	sudtBalance = big.NewInt(0)
+	for _, cell := range cells.Objects {
+		ckbBalance = new(big.Int).Add(ckbBalance, ckbBalanceExtractor(cell))
+		sudtBalance = new(big.Int).Add(sudtBalance, sudtBalanceExtractor(cell))
+	}
+	return ckbBalance, sudtBalance, nil
+}
+
+// FormatBalance formats the on-chain balances for the observers. The SUDT
+// balance is given in units of the smallest SUDT unit and shown with
+// sudtDecimals.
+func FormatBalance(ckbBal, sudtBal *big.Int, sudtDecimals uint8) string {
 	log.Printf("balances: ckb = %s || sudt = %s", ckbBal.String(), sudtBal.String())
-	balCKByte, _ := ShannonToCKByte(ckbBal).Float64()
 	return fmt.Sprintf("[green]%s\t[yellow]%s[white]",
-		strconv.FormatFloat(balCKByte, 'f', 2, 64)+" CKByte",
-		fmt.Sprintf("%v", sudtBal.Int64())+" SUDT")
+		NewAmount(ckbBal, CKBytesDecimals).String()+" CKByte",
+		NewAmount(sudtBal, sudtDecimals).String()+" SUDT")
 }
@@ -4,12 +4,10 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
-	"strconv"
 
 	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/wallet"
-	"perun.network/perun-ckb-backend/channel/asset"
 	address2 "perun.network/perun-ckb-backend/wallet/address"
 	asset2 "perun.network/perun-demo-tui/asset"
 )
@@ -17,39 +15,46 @@ import (
 type PaymentChannel struct {
 	state   *channel.State
 	parties []wallet.Address
-	assets  []channel.Asset
+	idx     channel.Index
 }
 
-// newPaymentChannel creates a new payment channel.
-func NewPaymentChannel(state *channel.State, parties []wallet.Address, assets []channel.Asset) *PaymentChannel {
+// NewPaymentChannel creates a new payment channel in which we are the
+// participant with index idx. Its assets are the assets of state.
+func NewPaymentChannel(state *channel.State, parties []wallet.Address, idx channel.Index) *PaymentChannel {
 	return &PaymentChannel{
 		state:   state,
 		parties: parties,
-		assets:  assets,
+		idx:     idx,
 	}
 }
 
-func FormatState(c *PaymentChannel, state *channel.State, network types.Network, assetRegister asset2.Register) string {
+// PartyIndex returns the index of addr in the participant list parties.
+func PartyIndex(addr wallet.Address, parties []wallet.Address) (channel.Index, error) {
+	for i, p := range parties {
+		if addr.Equal(p) {
+			return channel.Index(i), nil
+		}
+	}
+	return 0, fmt.Errorf("address %v is not a channel participant", addr)
+}
+
+// FormatState formats the given state of channel c for the observers. The
+// balances are shown with the decimals of their assets.
+func FormatState(c *PaymentChannel, state *channel.State, network types.Network, assetRegister asset2.Register, decimals Decimals) string {
 	id := state.ID
 	fstPartyPaymentAddr, _ := address2.AsParticipant(c.parties[0]).ToCKBAddress(network).Encode()
 	sndPartyPaymentAddr, _ := address2.AsParticipant(c.parties[1]).ToCKBAddress(network).Encode()
-	balAStrings := make([]string, len(c.assets))
-	balBStrings := make([]string, len(c.assets))
-	for i, a := range c.assets {
-		switch a := a.(type) {
-		case *asset.Asset:
-			if a.IsCKBytes {
-				balA, _ := ShannonToCKByte(state.Allocation.Balance(0, a)).Float64()
-				balAStrings[i] = strconv.FormatFloat(balA, 'f', 2, 64)
-				balB, _ := ShannonToCKByte(state.Allocation.Balance(1, a)).Float64()
-				balBStrings[i] = strconv.FormatFloat(balB, 'f', 2, 64)
-			} else {
-				balAStrings[i] = state.Allocation.Balance(0, a).String()
-				balBStrings[i] = state.Allocation.Balance(1, a).String()
-			}
-		default:
-			log.Fatalf("unsupported asset type: %T", a)
+	assets := c.Assets()
+	balAStrings := make([]string, len(assets))
+	balBStrings := make([]string, len(assets))
+	for i, a := range assets {
+		// Balances of unsupported assets are shown in their smallest unit.
+		d, err := decimals.Of(a)
+		if err != nil {
+			log.Printf("Formatting balance of unsupported asset: %v", err)
 		}
+		balAStrings[i] = NewAmount(state.Allocation.Balance(0, a), d).String()
+		balBStrings[i] = NewAmount(state.Allocation.Balance(1, a), d).String()
 	}
 
 	ret := fmt.Sprintf(
@@ -57,11 +62,11 @@ func FormatState(c *PaymentChannel, state *channel.State, network types.Network,
 		hex.EncodeToString(id[:]),
 	)
 	ret += fmt.Sprintf("%s:\n", fstPartyPaymentAddr)
-	for i, a := range c.assets {
+	for i, a := range assets {
 		ret += fmt.Sprintf("    [green]%s[white] %s\n", balAStrings[i], assetRegister.GetName(a))
 	}
 	ret += fmt.Sprintf("%s:\n", sndPartyPaymentAddr)
-	for i, a := range c.assets {
+	for i, a := range assets {
 		ret += fmt.Sprintf("    [green]%s[white] %s\n", balBStrings[i], assetRegister.GetName(a))
 	}
 	ret += fmt.Sprintf("Final: [green]%t[white]\nVersion: [green]%d[white]", state.IsFinal, state.Version)
@@ -71,3 +76,23 @@ func FormatState(c *PaymentChannel, state *channel.State, network types.Network,
 func (c PaymentChannel) State() *channel.State {
 	return c.state.Clone()
 }
+
+// ID returns the ID of the channel.
+func (c PaymentChannel) ID() channel.ID {
+	return c.state.ID
+}
+
+// Parties returns the participants of the channel in channel order.
+func (c PaymentChannel) Parties() []wallet.Address {
+	return c.parties
+}
+
+// Idx returns our index in the channel's participant list.
+func (c PaymentChannel) Idx() channel.Index {
+	return c.idx
+}
+
+// Assets returns the assets of the channel.
+func (c PaymentChannel) Assets() []channel.Asset {
+	return c.state.Allocation.Assets
+}
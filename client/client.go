@@ -2,14 +2,16 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"time"
 
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/nervosnetwork/ckb-sdk-go/v2/rpc"
 	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"perun.network/channel-service/rpc/proto"
 	gpchannel "perun.network/go-perun/channel"
@@ -17,32 +19,57 @@ import (
 	"perun.network/go-perun/wire/protobuf"
 	perunproto "perun.network/go-perun/wire/protobuf"
 	"perun.network/perun-ckb-backend/channel/asset"
-	"perun.network/perun-ckb-backend/wallet"
 	"perun.network/perun-ckb-backend/wallet/address"
 	asset2 "perun.network/perun-demo-tui/asset"
 	vc "perun.network/perun-demo-tui/client"
+	"perun.network/perun-nervos-demo/deployment"
+	"perun.network/perun-nervos-demo/dispute"
+	"perun.network/perun-nervos-demo/readiness"
+	"perun.network/perun-nervos-demo/signer"
 	"perun.network/perun-nervos-demo/wallet_service"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
 	"polycry.pt/poly-go/sync"
 )
 
+const (
+	// eventRetryInterval is the time after which a client subscribes again to
+	// the events of its wallet service if the subscription ended.
+	eventRetryInterval = time.Second
+	// openEventTimeout is the time in which the wallet service has to report
+	// the initial state of a channel which we opened.
+	openEventTimeout = 10 * time.Second
+)
+
 type WalletClient struct {
 	observerMutex sync.Mutex
 	balanceMutex  sync.Mutex
+	channelMutex  sync.Mutex
 	observers     []vc.Observer
-	Channel       *PaymentChannel
+	channels      map[gpchannel.ID]*PaymentChannel
+	channelAdded  map[gpchannel.ID]chan struct{} // Closed once the channel is known.
+	latest        gpchannel.ID
 	Name          string
 	balance       *big.Int
 	sudtBalance   *big.Int
-	Account       *wallet.Account
+	signer        signer.Signer
 	Network       types.Network
 	assetRegister asset2.Register
+	decimals      Decimals
+
+	proposalMutex sync.Mutex // Serializes channel proposals.
+
+	payoutMutex sync.Mutex
+	payouts     map[gpchannel.ID]*Payout // Payouts of the closed channels.
 
 	ChannelService proto.ChannelServiceClient
-	WalletServer   *wallet_service.MyWalletService
-	walletService  proto.WalletServiceClient
+	disputeService *dispute.Client
+	wallet         *wallet_service.AccountClient
+	// serviceConns are the connections to the wallet and channel service,
+	// whose readiness is awaited by WaitReady.
+	serviceConns []grpc.ClientConnInterface
 
-	parties []gpwallet.Address
-	assets  []gpchannel.Asset
+	challengeDuration uint64 // Default on-chain challenge duration in seconds.
+	challengeBounds   wallet_service.ChallengeDurationBounds
 
 	rpcClient rpc.Client
 }
@@ -51,73 +78,95 @@ func NewWalletClient(
 	name string,
 	network types.Network,
 	rpcURL string,
-	parties []gpwallet.Address,
-	assets []gpchannel.Asset,
 	wsURL string,
+	wsCreds credentials.TransportCredentials,
 	csURL string,
-	account *wallet.Account,
-	key *secp256k1.PrivateKey,
+	csCreds credentials.TransportCredentials,
+	sgn signer.Signer,
 	assetRegister asset2.Register,
-	wg *sync.WaitGroup,
+	decimals Decimals,
+	challengeDuration uint64,
+	challengeBounds wallet_service.ChallengeDurationBounds,
 ) (*WalletClient, error) {
-
-	// Create wallet service server / client
-	wss, err := wallet_service.NewWalletServiceServer(name, account, key, network, wsURL, wg)
+	balanceRPC, err := rpc.Dial(rpcURL)
 	if err != nil {
-		return nil, fmt.Errorf("creating wallet service server: %w", err)
+		return nil, err
 	}
 
-	conn, err := grpc.Dial(wsURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// The client follows and directs its account through the account API of
+	// the wallet service, which is unencrypted without wsCreds.
+	if wsCreds == nil {
+		wsCreds = insecure.NewCredentials()
+	}
+	wsConn, err := grpc.Dial(wsURL, grpc.WithTransportCredentials(wsCreds))
 	if err != nil {
-		return nil, fmt.Errorf("dialing wallet service server: %w", err)
+		return nil, fmt.Errorf("dialing wallet service: %w", err)
 	}
-	wsc := proto.NewWalletServiceClient(conn)
-
-	// Create channel service client
-	conn, err = grpc.Dial(csURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	wallet, err := wallet_service.NewAccountClient(wsConn, sgn.Address())
 	if err != nil {
-		return nil, fmt.Errorf("dialing channel service server: %w", err)
+		return nil, err
 	}
-	csc := proto.NewChannelServiceClient(conn)
 
-	balanceRPC, err := rpc.Dial(rpcURL)
+	// Create channel service client, which is unencrypted without csCreds.
+	if csCreds == nil {
+		csCreds = insecure.NewCredentials()
+	}
+	conn, err := grpc.Dial(csURL, grpc.WithTransportCredentials(csCreds))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("dialing channel service server: %w", err)
 	}
+	csc := proto.NewChannelServiceClient(conn)
 
 	p := &WalletClient{
-		Name:           name,
-		balance:        big.NewInt(0),
-		sudtBalance:    big.NewInt(0),
-		Account:        account,
-		Network:        network,
-		parties:        parties,
-		assets:         assets,
-		assetRegister:  assetRegister,
-		rpcClient:      balanceRPC,
-		walletService:  wsc,
-		WalletServer:   wss,
-		ChannelService: csc,
-	}
-	wss.SetOnUpdate(p.NotifyAllState)
+		Name:              name,
+		balance:           big.NewInt(0),
+		sudtBalance:       big.NewInt(0),
+		channels:          make(map[gpchannel.ID]*PaymentChannel),
+		channelAdded:      make(map[gpchannel.ID]chan struct{}),
+		payouts:           make(map[gpchannel.ID]*Payout),
+		signer:            sgn,
+		Network:           network,
+		challengeDuration: challengeDuration,
+		challengeBounds:   challengeBounds,
+		assetRegister:     assetRegister,
+		decimals:          decimals,
+		rpcClient:         balanceRPC,
+		wallet:            wallet,
+		ChannelService:    csc,
+		disputeService:    dispute.NewClient(conn),
+		serviceConns:      []grpc.ClientConnInterface{wsConn, conn},
+	}
 
+	go p.consumeEvents()
 	go p.PollBalances()
 	return p, nil
 }
 
+// WaitReady waits until the wallet service and the channel service of the
+// client are ready, or returns an error if ctx is done before. The open
+// channels are loaded from the wallet service once it is ready.
+func (p *WalletClient) WaitReady(ctx context.Context) error {
+	for _, cc := range p.serviceConns {
+		if err := readiness.WaitReady(ctx, cc); err != nil {
+			return fmt.Errorf("waiting for the services of %s: %w", p.Name, err)
+		}
+	}
+	return p.loadChannels(ctx)
+}
+
 // WalletAddress returns the wallet address of the client.
 func (p *WalletClient) WalletAddress() gpwallet.Address {
-	return p.Account.Address()
+	return p.signer.Address()
 }
 
 func (p *WalletClient) Register(observer vc.Observer) {
 	p.observerMutex.Lock()
 	defer p.observerMutex.Unlock()
 	p.observers = append(p.observers, observer)
-	if p.Channel != nil {
-		observer.UpdateState(FormatState(p.Channel, p.Channel.State(), p.Network, p.assetRegister))
+	for _, ch := range p.Channels() {
+		observer.UpdateState(FormatState(ch, ch.State(), p.Network, p.assetRegister, p.decimals))
 	}
-	observer.UpdateBalance(FormatBalance(p.GetBalance(), p.GetSudtBalance()))
+	observer.UpdateBalance(FormatBalance(p.GetBalance(), p.GetSudtBalance(), p.decimals.common()))
 }
 
 func (p *WalletClient) GetBalance() *big.Int {
@@ -145,10 +194,22 @@ func (p *WalletClient) Deregister(observer vc.Observer) {
 }
 
 func (p *WalletClient) NotifyAllState(from, to *gpchannel.State) {
+	p.notifyState(from, to, nil)
+}
+
+// notifyState notifies the observers about the update of a channel from the
+// state from to the state to. The participants of the channel in channel order
+// are only needed if the channel is new to the client.
+func (p *WalletClient) notifyState(from, to *gpchannel.State, parties []gpwallet.Address) {
 	p.observerMutex.Lock()
 	defer p.observerMutex.Unlock()
-	p.Channel = NewPaymentChannel(to, p.parties, p.assets)
-	str := FormatState(p.Channel, to, p.Network, p.assetRegister)
+	ch, err := p.newChannel(to, parties)
+	if err != nil {
+		log.Printf("Ignoring state of channel %x for client %s: %v", to.ID, p.Name, err)
+		return
+	}
+	p.setChannel(ch)
+	str := FormatState(ch, to, p.Network, p.assetRegister, p.decimals)
 	log.Printf("Notifying all observers of state change for client %s", p.Name)
 	for _, o := range p.observers {
 		o.UpdateState(str)
@@ -157,7 +218,7 @@ func (p *WalletClient) NotifyAllState(from, to *gpchannel.State) {
 
 func (p *WalletClient) NotifyAllBalance(ckbBal int64) {
 	// TODO: This is hacky and gruesome, but we make this work for this demo.
-	str := FormatBalance(new(big.Int).SetInt64(ckbBal), p.GetSudtBalance())
+	str := FormatBalance(new(big.Int).SetInt64(ckbBal), p.GetSudtBalance(), p.decimals.common())
 	for _, o := range p.observers {
 		o.UpdateBalance(str)
 	}
@@ -168,68 +229,120 @@ func (p *WalletClient) DisplayName() string {
 }
 
 func (p *WalletClient) DisplayAddress() string {
-	addr, _ := address.AsParticipant(p.Account.Address()).ToCKBAddress(p.Network).Encode()
+	addr, _ := address.AsParticipant(p.WalletAddress()).ToCKBAddress(p.Network).Encode()
 	return addr
 }
 
-// OpenChannel opens a new channel with the specified peer and funding.
-func (p *WalletClient) OpenChannel(peer gpwallet.Address, amounts map[gpchannel.Asset]float64) {
+// Funding is the initial balance of both participants in one asset of a
+// channel. Either side may contribute nothing.
+type Funding struct {
+	Own  Amount // Our initial balance.
+	Peer Amount // Peer's initial balance.
+}
+
+// Decimals returns the decimals of the assets with which the client parses and
+// shows amounts.
+func (p *WalletClient) Decimals() Decimals {
+	return p.decimals
+}
+
+// ChallengeDuration returns the default on-chain challenge duration of new
+// channels in seconds.
+func (p *WalletClient) ChallengeDuration() uint64 {
+	return p.challengeDuration
+}
+
+// OpenChannel opens a new channel with the specified peer and funding. The
+// challenge duration is given in seconds and must be within the client's
+// policy bounds.
+func (p *WalletClient) OpenChannel(peer gpwallet.Address, funding map[gpchannel.Asset]Funding, challengeDuration uint64) error {
 	// We define the channel participants. The proposer always has index 0. Here
 	// we use the on-chain addresses as off-chain addresses, but we could also
 	// use different ones.
+	const op = "open channel"
 	log.Println("OpenChannel called")
+	if len(funding) == 0 {
+		return invalidInput(op, "no assets to fund")
+	}
+	if err := p.challengeBounds.Check(challengeDuration); err != nil {
+		return invalidInput(op, "%v", err)
+	}
 
-	assets := make([]gpchannel.Asset, len(amounts))
-	i := 0
-	for a := range amounts {
-		assets[i] = a
-		i++
+	assets := make([]gpchannel.Asset, 0, len(funding)+1)
+	var ckbAsset gpchannel.Asset
+	for a := range funding {
+		ckb, ok := a.(*asset.Asset)
+		if !ok {
+			return invalidInput(op, "unsupported asset of type %T", a)
+		}
+		assets = append(assets, a)
+		if ckb.IsCKBytes {
+			ckbAsset = a
+		}
+	}
+	if ckbAsset == nil {
+		ckbAsset = asset.NewCKBytesAsset()
+		assets = append(assets, ckbAsset)
 	}
 
 	// We create an initial allocation which defines the starting balances.
 	initAlloc := gpchannel.NewAllocation(2, assets...)
 	log.Println(initAlloc.Assets)
-	for a, amount := range amounts {
-		switch a := a.(type) {
-		case *asset.Asset:
-			if a.IsCKBytes {
-				initAlloc.SetAssetBalances(a, []gpchannel.Bal{
-					CKByteToShannon(big.NewFloat(amount)), // Our initial balance.
-					CKByteToShannon(big.NewFloat(amount)), // Peer's initial balance.
-				})
-			} else {
-				intAmount := new(big.Int).SetUint64(uint64(amount))
-				initAlloc.SetAssetBalances(a, []gpchannel.Bal{
-					intAmount, // Our initial balance.
-					intAmount, // Peer's initial balance.
-				})
+	minCapacity := new(big.Int).SetUint64(deployment.PFLSMinCapacity)
+	for a, f := range funding {
+		bals := make([]gpchannel.Bal, 2)
+		for i, amount := range []Amount{f.Own, f.Peer} {
+			bal, err := p.decimals.units(a, amount)
+			if err != nil {
+				return invalidInput(op, "%v", err)
 			}
-		default:
-			log.Fatalf("Asset is not of type *asset.Asset")
+			bals[i] = bal
+		}
+		if a == ckbAsset {
+			for _, bal := range bals {
+				if bal.Sign() != 0 && bal.Cmp(minCapacity) < 0 {
+					return invalidInput(op, "CKBytes contribution %s is below the minimum funding cell capacity %d Shannon", bal, uint64(deployment.PFLSMinCapacity))
+				}
+			}
+		}
+		initAlloc.SetAssetBalances(a, bals)
+	}
+	if isEmpty(initAlloc) {
+		return invalidInput(op, "channel must be funded by at least one participant")
+	}
+	// Each participant funds its own cell, which has to hold at least the
+	// minimum capacity. Participants who contribute no CKBytes therefore
+	// contribute the minimum, which is paid back to them on settlement.
+	for i := range initAlloc.Balances[0] {
+		if initAlloc.Balance(gpchannel.Index(i), ckbAsset).Sign() == 0 {
+			initAlloc.SetBalance(gpchannel.Index(i), ckbAsset, minCapacity)
 		}
-
 	}
 	log.Println("Created Allocation")
 
 	// Create the channel open request
 
-	requester, err := p.Account.Address().MarshalBinary()
+	requester, err := p.WalletAddress().MarshalBinary()
 	if err != nil {
-		log.Fatalf("Failed to marshal requester address: %v", err)
+		return fmt.Errorf("%s: marshalling requester address: %w", op, err)
 	}
 
+	if peer == nil {
+		return invalidInput(op, "missing peer")
+	}
+	if peer.Equal(p.WalletAddress()) {
+		return invalidInput(op, "cannot open a channel with ourselves")
+	}
 	peerBytes, err := peer.MarshalBinary()
 	if err != nil {
-		log.Fatalf("Failed to marshal peer address: %v", err)
+		return invalidInput(op, "marshalling peer address: %v", err)
 	}
 
 	protAlloc, err := perunproto.FromAllocation(*initAlloc)
 	if err != nil {
-		log.Fatalf("Failed to convert allocation to protobuf: %v", err)
+		return invalidInput(op, "converting allocation to protobuf: %v", err)
 	}
 
-	challengeDuration := uint64(10) // On-chain challenge duration in seconds.
-
 	log.Println("Created Proposal")
 	openChannelRequest := &proto.ChannelOpenRequest{
 		Requester:         requester,
@@ -238,48 +351,75 @@ func (p *WalletClient) OpenChannel(peer gpwallet.Address, amounts map[gpchannel.
 		ChallengeDuration: challengeDuration,
 	}
 
-	// Use channel service to send proposal
+	// Use channel service to send proposal. The wallet service only signs the
+	// initial state of channels which we proposed or accepted, and it records
+	// their participants.
+	p.proposalMutex.Lock()
+	defer p.proposalMutex.Unlock()
+	parties := []gpwallet.Address{p.WalletAddress(), peer}
+	revoke, err := p.wallet.AuthorizeProposal(context.Background(), parties, initAlloc)
+	if err != nil {
+		return callError(op, err)
+	}
+	defer revoke()
 	resp, err := p.ChannelService.OpenChannel(context.Background(), openChannelRequest)
 	if err != nil {
-		log.Fatalf("Failed to open channel: %v", err)
+		return callError(op, err)
 	}
 
 	// Check if the channel open request was rejected
-	if rej, ok := resp.Msg.(*proto.ChannelOpenResponse_Rejected); ok {
-		log.Fatalf("Channel open request was rejected, reason: %v", rej.Rejected.Reason)
+	if rej := resp.GetRejected(); rej != nil {
+		return &RejectedError{Op: op, Reason: rej.Reason}
 	}
 
+	// The initial state of the channel arrives on the event stream, which may
+	// lag behind the response.
+	var id gpchannel.ID
+	copy(id[:], resp.GetChannelId())
+	ctx, cancel := context.WithTimeout(context.Background(), openEventTimeout)
+	defer cancel()
+	if err := p.awaitChannel(ctx, id); err != nil {
+		return fmt.Errorf("%s: channel %x was not reported by the wallet service: %w", op, id, err)
+	}
 	log.Println("Sent Channel")
-
+	return nil
 }
 
-func (p *WalletClient) SendPaymentToPeer(amounts map[gpchannel.Asset]float64) {
+// SendPaymentToPeer sends a payment to the peer in the channel with the given ID.
+func (p *WalletClient) SendPaymentToPeer(id gpchannel.ID, amounts map[gpchannel.Asset]Amount) error {
+	const op = "send payment"
 	log.Println("SendPaymentToPeer called")
-	if !p.HasOpenChannel() {
-		return
-	}
-	var actor gpchannel.Index
-	if p.Name == "Alice" {
-		actor = 0
-	} else {
-		actor = 1
+	ch := p.Channel(id)
+	if ch == nil {
+		return invalidInput(op, "no open channel with ID %x", id)
 	}
+	actor := ch.Idx()
 	peer := 1 - actor
+	// Work on a copy so that a rejected update leaves the channel untouched.
+	state := ch.State()
 	for a, amount := range amounts {
-		if amount < 0 {
-			continue
+		units, err := p.decimals.units(a, amount)
+		if err != nil {
+			return invalidInput(op, "%v", err)
+		}
+		if _, ok := state.Allocation.AssetIndex(a); !ok {
+			return invalidInput(op, "asset %s is not in channel %x", p.assetRegister.GetName(a), id)
 		}
-		if a.(*asset.Asset).IsCKBytes {
-			shannonAmount := CKByteToShannon(big.NewFloat(amount))
-			p.Channel.state.Allocation.TransferBalance(actor, peer, a, shannonAmount)
-		} else {
-			intAmount := new(big.Int).SetUint64(uint64(amount))
-			p.Channel.state.Allocation.TransferBalance(actor, peer, a, intAmount)
+		if bal := state.Allocation.Balance(actor, a); bal.Cmp(units) < 0 {
+			return invalidInput(op, "amount %s exceeds our balance %s", amount, NewAmount(bal, amount.Decimals()))
 		}
+		state.Allocation.TransferBalance(actor, peer, a, units)
+	}
+	// The wallet service only signs updates which decrease our balance if we
+	// proposed them.
+	revoke, err := p.wallet.AuthorizeUpdate(context.Background(), state)
+	if err != nil {
+		return callError(op, err)
 	}
-	protoUpdate, err := protobuf.FromState(p.Channel.State())
+	defer revoke()
+	protoUpdate, err := protobuf.FromState(state)
 	if err != nil {
-		log.Fatalf("Failed to convert state to protobuf: %v", err)
+		return invalidInput(op, "converting state to protobuf: %v", err)
 	}
 	updateChannelRequest := &proto.ChannelUpdateRequest{
 		State: protoUpdate,
@@ -288,85 +428,366 @@ func (p *WalletClient) SendPaymentToPeer(amounts map[gpchannel.Asset]float64) {
 	log.Println("Sending payment to peer")
 	resp, err := p.ChannelService.UpdateChannel(context.Background(), updateChannelRequest)
 	if err != nil {
-		log.Fatalf("Failed to update channel: %v", err)
+		return callError(op, err)
 	}
 
-	if rej, ok := resp.Msg.(*proto.ChannelUpdateResponse_Rejected); ok {
-		log.Fatalf("Channel close request was rejected, reason: %v", rej.Rejected.Reason)
+	if rej := resp.GetRejected(); rej != nil {
+		return &RejectedError{Op: op, Reason: rej.Reason}
 	}
 
-	p.NotifyAllState(p.Channel.State(), p.Channel.State())
+	if update := resp.GetUpdate(); update != nil && update.State != nil {
+		if newState, err := protobuf.ToState(update.State); err == nil {
+			state = newState
+		}
+	}
+	p.NotifyAllState(ch.State(), state)
+	return nil
 }
 
-func (p *WalletClient) Settle() {
+// Settle closes the channel with the given ID and withdraws the funds. The
+// channel is removed once the wallet service observed the settlement, and an
+// error is returned if the wallet service could not be told to watch for it.
+func (p *WalletClient) Settle(id gpchannel.ID) error {
+	const op = "settle"
 	log.Println("Settle called")
-	if !p.HasOpenChannel() {
-		return
+	ch := p.Channel(id)
+	if ch == nil {
+		return invalidInput(op, "no open channel with ID %x", id)
 	}
 
 	closeChannelRequest := &proto.ChannelCloseRequest{
-		ChannelId: p.Channel.State().ID[:],
+		ChannelId: id[:],
 	}
 
 	resp, err := p.ChannelService.CloseChannel(context.Background(), closeChannelRequest)
 	if err != nil {
-		log.Fatalf("Failed to close channel: %v", err)
+		return callError(op, err)
+	}
+
+	if rej := resp.GetRejected(); rej != nil {
+		return &RejectedError{Op: op, Reason: rej.Reason}
+	}
+
+	// The channel service submits the settlement before it responds.
+	if err := p.closeChannel(ch); err != nil {
+		return callError(op, err)
+	}
+	return nil
+}
+
+// ForceClose closes the channel with the given ID without the cooperation of
+// the peer. The latest state is registered on-chain and the funds are
+// withdrawn after the challenge period. The observers and, if it is not nil,
+// progress are notified about every phase which the channel enters. As with
+// Settle, the channel is removed once the wallet service observed the
+// settlement.
+func (p *WalletClient) ForceClose(id gpchannel.ID, progress func(gpchannel.Phase)) error {
+	const op = "force close"
+	log.Println("ForceClose called")
+	ch := p.Channel(id)
+	if ch == nil {
+		return invalidInput(op, "no open channel with ID %x", id)
+	}
+
+	err := p.disputeService.ForceClose(context.Background(), id, func(phase gpchannel.Phase) {
+		log.Printf("Force close of channel %x: %v", id, phase)
+		p.notifyProgress(ch, fmt.Sprintf("[yellow]Force close[white]: %s", phaseDescription(phase)))
+		if progress != nil {
+			progress(phase)
+		}
+	})
+	if err != nil {
+		return callError(op, err)
+	}
+
+	if err := p.closeChannel(ch); err != nil {
+		return callError(op, err)
+	}
+	return nil
+}
+
+// notifyProgress shows the given progress of an operation on the channel ch to
+// all observers.
+func (p *WalletClient) notifyProgress(ch *PaymentChannel, progress string) {
+	str := FormatState(ch, ch.State(), p.Network, p.assetRegister, p.decimals) + "\n" + progress
+	p.observerMutex.Lock()
+	defer p.observerMutex.Unlock()
+	for _, o := range p.observers {
+		o.UpdateState(str)
+	}
+}
+
+// RestoreChannel restores all channels of the client from the channel
+// service's persistence.
+func (p *WalletClient) RestoreChannel() error {
+	const op = "restore channel"
+	if p.HasOpenChannel() {
+		log.Println("Channel is already online")
+		return nil
+	}
+
+	// Close Perun Client on Channel Service
+	log.Println("Closing perun client")
+	_, err := p.ChannelService.ClosePerunClient(context.Background(), &proto.ClosePerunClientRequest{})
+	if err != nil {
+		return callError(op, err)
 	}
 
-	if rej, ok := resp.Msg.(*proto.ChannelCloseResponse_Rejected); ok {
-		log.Fatalf("Channel close request was rejected, reason: %v", rej.Rejected.Reason)
+	// Reinit Perun Client on Channel Service
+	log.Println("Creating perun client")
+	resp, err := p.ChannelService.NewPerunClient(context.Background(), &proto.NewPerunClientRequest{})
+	if err != nil {
+		return callError(op, err)
 	}
 
-	p.Channel = nil
+	if !resp.Accepted {
+		return &RejectedError{Op: op, Reason: "perun client creation rejected"}
+	}
+
+	log.Println("Restoring channel")
+	// Restore the channel
+	resp2, err := p.ChannelService.RestoreChannels(context.Background(), &proto.RestoreChannelsRequest{})
+	if err != nil {
+		return callError(op, err)
+	}
 
+	if !resp2.Accepted {
+		return &RejectedError{Op: op, Reason: "channel restore request was rejected"}
+	}
+	log.Println("Channel restored")
+	return nil
 }
 
-func (p *WalletClient) RestoreChannel() {
-	if !p.HasOpenChannel() {
+// SyncChannels fetches the current channel state from the channel service.
+// This is needed if the channel service kept a channel open while the client
+// was not running.
+func (p *WalletClient) SyncChannels() error {
+	const op = "sync channels"
+	requester, err := p.WalletAddress().MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("%s: marshalling requester address: %w", op, err)
+	}
+	resp, err := p.ChannelService.GetChannels(context.Background(), &proto.GetChannelsRequest{Requester: requester})
+	if err != nil {
+		return callError(op, err)
+	}
+	// The channel service rejects the request if there are no channels.
+	if resp.GetRejected() != nil {
+		return nil
+	}
+	state, err := protobuf.ToState(resp.GetState())
+	if err != nil {
+		return fmt.Errorf("%s: decoding state: %w", op, err)
+	}
+	p.NotifyAllState(nil, state)
+	return nil
+}
 
-		// Close Perun Client on Channel Service
-		log.Println("Closing perun client")
-		_, err := p.ChannelService.ClosePerunClient(context.Background(), &proto.ClosePerunClientRequest{})
+// consumeEvents handles the events of the client's account at the wallet
+// service. The subscription is renewed whenever it ends, and the updates
+// which start every subscription bring the channels up to date.
+func (p *WalletClient) consumeEvents() {
+	for {
+		err := p.wallet.Subscribe(context.Background(), p.handleEvent)
 		if err != nil {
-			log.Fatalf("failed to close perun client: %s", err)
+			log.Printf("Event subscription of client %s ended: %v", p.Name, err)
 		}
+		time.Sleep(eventRetryInterval)
+	}
+}
 
-		// Reinit Perun Client on Channel Service
-		log.Println("Creating perun client")
-		resp, err := p.ChannelService.NewPerunClient(context.Background(), &proto.NewPerunClientRequest{})
+// handleEvent applies an event of the wallet service to the channels.
+func (p *WalletClient) handleEvent(e *walletpb.Event) {
+	switch e.Type {
+	case walletpb.EventType_EVENT_TYPE_UPDATE:
+		to, err := wallet_service.EventState(e)
 		if err != nil {
-			log.Fatalf("failed to create perun client: %s", err)
+			log.Printf("Ignoring update of channel %x for client %s: %v", e.ChannelId, p.Name, err)
+			return
 		}
-
-		if !resp.Accepted {
-			log.Fatalf("perun client creation rejected")
+		parties, err := wallet_service.EventParticipants(e)
+		if err != nil {
+			log.Printf("Ignoring update of channel %x for client %s: %v", e.ChannelId, p.Name, err)
+			return
 		}
-
-		log.Println("Restoring channel")
-		// Restore the channel
-		resp2, err := p.ChannelService.RestoreChannels(context.Background(), &proto.RestoreChannelsRequest{})
+		// The update of a final state may arrive after the channel was closed.
+		if p.hasPayout(to.ID) {
+			return
+		}
+		var from *gpchannel.State
+		if ch := p.Channel(to.ID); ch != nil {
+			// Updates are repeated when subscribing again.
+			if ch.State().Version >= to.Version {
+				return
+			}
+			from = ch.State()
+		}
+		p.notifyState(from, to, parties)
+	case walletpb.EventType_EVENT_TYPE_CLOSED:
+		final, err := wallet_service.EventState(e)
 		if err != nil {
-			log.Fatalf("Failed to restore channel: %v", err)
+			log.Printf("Ignoring close of channel %x for client %s: %v", e.ChannelId, p.Name, err)
+			return
 		}
+		paid := make([]*big.Int, len(e.Payout))
+		for i, amount := range e.Payout {
+			var ok bool
+			if paid[i], ok = new(big.Int).SetString(amount, 10); !ok {
+				log.Printf("Ignoring payout %q of channel %x for client %s", amount, e.ChannelId, p.Name)
+				paid = nil
+				break
+			}
+		}
+		p.channelClosed(final.ID, &final.Allocation, paid)
+	default:
+		log.Printf("Wallet event of client %s: %v %x", p.Name, e.Type, e.ChannelId)
+	}
+}
 
-		if !resp2.Accepted {
-			log.Fatalf("Channel restore request was rejected")
+// loadChannels rebuilds the open channels from the states which the wallet
+// service kept, so that they are known before the next update arrives.
+func (p *WalletClient) loadChannels(ctx context.Context) error {
+	chs, err := p.wallet.Channels(ctx)
+	if err != nil {
+		return fmt.Errorf("loading the channels of %s: %w", p.Name, err)
+	}
+	for _, c := range chs {
+		ch, err := p.newChannel(c.State, c.Participants)
+		if err != nil {
+			log.Printf("Ignoring stored state of channel %x for client %s: %v", c.State.ID, p.Name, err)
+			continue
 		}
-		log.Println("Channel restored")
+		p.setChannel(ch)
+	}
+	return nil
+}
 
-		return
+// ChannelHistory returns all states of the channel with the given ID on which
+// both participants agreed, ordered by version.
+func (p *WalletClient) ChannelHistory(id gpchannel.ID) ([]*gpchannel.State, error) {
+	states, err := p.wallet.History(context.Background(), id)
+	if err != nil {
+		return nil, callError("channel history", err)
 	}
-	log.Println("Channel is already online")
+	return states, nil
 }
 
+// HasOpenChannel returns true iff the client has at least one open channel.
 func (p *WalletClient) HasOpenChannel() bool {
-	return p.Channel != nil
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	return len(p.channels) > 0
 }
 
-// GetOpenChannelAssets returns the assets of the client's currently open channel.
-func (p *WalletClient) GetOpenChannelAssets() []gpchannel.Asset {
-	if !p.HasOpenChannel() {
+// HasChannel returns true iff the client has an open channel with the given ID.
+func (p *WalletClient) HasChannel(id gpchannel.ID) bool {
+	return p.Channel(id) != nil
+}
+
+// GetOpenChannelAssets returns the assets of the open channel with the given ID.
+func (p *WalletClient) GetOpenChannelAssets(id gpchannel.ID) []gpchannel.Asset {
+	ch := p.Channel(id)
+	if ch == nil {
 		return nil
 	}
-	return p.Channel.assets
+	return ch.Assets()
+}
+
+// Channel returns the open channel with the given ID or nil if there is none.
+func (p *WalletClient) Channel(id gpchannel.ID) *PaymentChannel {
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	return p.channels[id]
+}
+
+// Channels returns all open channels of the client.
+func (p *WalletClient) Channels() []*PaymentChannel {
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	chs := make([]*PaymentChannel, 0, len(p.channels))
+	for _, ch := range p.channels {
+		chs = append(chs, ch)
+	}
+	return chs
+}
+
+// LatestChannel returns the most recently updated open channel or nil if
+// there is none.
+func (p *WalletClient) LatestChannel() *PaymentChannel {
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	return p.channels[p.latest]
+}
+
+// newChannel creates the PaymentChannel for the given state. Our participant
+// index is kept from an already known channel with the same ID and is
+// otherwise derived by looking up our address in the given participants of
+// the channel.
+func (p *WalletClient) newChannel(state *gpchannel.State, parties []gpwallet.Address) (*PaymentChannel, error) {
+	if ch := p.Channel(state.ID); ch != nil {
+		return NewPaymentChannel(state, ch.parties, ch.idx), nil
+	}
+	if parties == nil {
+		return nil, errors.New("unknown channel participants")
+	}
+	idx, err := PartyIndex(p.WalletAddress(), parties)
+	if err != nil {
+		return nil, err
+	}
+	return NewPaymentChannel(state, parties, idx), nil
+}
+
+func (p *WalletClient) setChannel(ch *PaymentChannel) {
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	p.channels[ch.ID()] = ch
+	p.latest = ch.ID()
+	if added, ok := p.channelAdded[ch.ID()]; ok {
+		close(added)
+		delete(p.channelAdded, ch.ID())
+	}
+}
+
+// awaitChannel waits until the channel with the given ID is known, or returns
+// an error if ctx is done before.
+func (p *WalletClient) awaitChannel(ctx context.Context, id gpchannel.ID) error {
+	p.channelMutex.Lock()
+	if _, ok := p.channels[id]; ok {
+		p.channelMutex.Unlock()
+		return nil
+	}
+	added, ok := p.channelAdded[id]
+	if !ok {
+		added = make(chan struct{})
+		p.channelAdded[id] = added
+	}
+	p.channelMutex.Unlock()
+	select {
+	case <-added:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// removeChannel removes the channel with the given ID and returns it, or nil
+// if there is none.
+func (p *WalletClient) removeChannel(id gpchannel.ID) *PaymentChannel {
+	p.channelMutex.Lock()
+	defer p.channelMutex.Unlock()
+	ch, ok := p.channels[id]
+	if !ok {
+		return nil
+	}
+	delete(p.channels, id)
+	if p.latest != id {
+		return ch
+	}
+	// Fall back to any other open channel.
+	p.latest = gpchannel.ID{}
+	for other := range p.channels {
+		p.latest = other
+		break
+	}
+	return ch
 }
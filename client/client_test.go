@@ -0,0 +1,364 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"perun.network/channel-service/rpc/proto"
+	gpchannel "perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-nervos-demo/signer"
+	"perun.network/perun-nervos-demo/wallet_service"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+)
+
+// fakeChannelService answers channel proposals with open, accepts every close
+// and restores channels with restore.
+type fakeChannelService struct {
+	proto.ChannelServiceClient
+	open    func(*proto.ChannelOpenRequest) (*proto.ChannelOpenResponse, error)
+	restore func()
+}
+
+func (s *fakeChannelService) OpenChannel(_ context.Context, in *proto.ChannelOpenRequest, _ ...grpc.CallOption) (*proto.ChannelOpenResponse, error) {
+	return s.open(in)
+}
+
+func (s *fakeChannelService) CloseChannel(context.Context, *proto.ChannelCloseRequest, ...grpc.CallOption) (*proto.ChannelCloseResponse, error) {
+	return new(proto.ChannelCloseResponse), nil
+}
+
+func (s *fakeChannelService) ClosePerunClient(context.Context, *proto.ClosePerunClientRequest, ...grpc.CallOption) (*proto.ClosePerunClientResponse, error) {
+	return &proto.ClosePerunClientResponse{Accepted: true}, nil
+}
+
+func (s *fakeChannelService) NewPerunClient(context.Context, *proto.NewPerunClientRequest, ...grpc.CallOption) (*proto.NewPerunClientResponse, error) {
+	return &proto.NewPerunClientResponse{Accepted: true}, nil
+}
+
+func (s *fakeChannelService) RestoreChannels(context.Context, *proto.RestoreChannelsRequest, ...grpc.CallOption) (*proto.RestoreChannelsResponse, error) {
+	s.restore()
+	return &proto.RestoreChannelsResponse{Accepted: true}, nil
+}
+
+// fakeAccount grants every authorization of the account API, answers reports
+// of closed channels with closeErr and keeps the open channels channels.
+type fakeAccount struct {
+	walletpb.UnimplementedWalletAccountServer
+	closeErr error
+	channels []*walletpb.Channel
+}
+
+func (a *fakeAccount) GetChannels(context.Context, *walletpb.AccountRequest) (*walletpb.ChannelsResponse, error) {
+	return &walletpb.ChannelsResponse{Channels: a.channels}, nil
+}
+
+func (fakeAccount) AuthorizeProposal(context.Context, *walletpb.AuthorizeProposalRequest) (*walletpb.Authorization, error) {
+	return &walletpb.Authorization{Id: 1}, nil
+}
+
+func (a *fakeAccount) CloseChannel(context.Context, *walletpb.ChannelRequest) (*walletpb.CloseChannelResponse, error) {
+	if a.closeErr != nil {
+		return nil, a.closeErr
+	}
+	return new(walletpb.CloseChannelResponse), nil
+}
+
+func (fakeAccount) Revoke(context.Context, *walletpb.RevokeRequest) (*walletpb.RevokeResponse, error) {
+	return new(walletpb.RevokeResponse), nil
+}
+
+func newTestSigner(t *testing.T) signer.Signer {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	return signer.NewLocalSigner(key, types.NetworkTest)
+}
+
+// newTestClient creates a client whose wallet service is account.
+func newTestClient(t *testing.T, account *fakeAccount) *WalletClient {
+	s := grpc.NewServer()
+	walletpb.RegisterWalletAccountServer(s, account)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	sgn := newTestSigner(t)
+	wallet, err := wallet_service.NewAccountClient(conn, sgn.Address())
+	require.NoError(t, err)
+	return &WalletClient{
+		Name:          "alice",
+		channels:      make(map[gpchannel.ID]*PaymentChannel),
+		channelAdded:  make(map[gpchannel.ID]chan struct{}),
+		payouts:       make(map[gpchannel.ID]*Payout),
+		signer:        sgn,
+		Network:       types.NetworkTest,
+		assetRegister: testRegister{assets: []gpchannel.Asset{asset.NewCKBytesAsset()}},
+		wallet:        wallet,
+	}
+}
+
+// testRegister names the assets by their index in assets.
+type testRegister struct {
+	assets []gpchannel.Asset
+}
+
+func (r testRegister) GetAsset(name string) gpchannel.Asset {
+	for i, a := range r.assets {
+		if fmt.Sprint(i) == name {
+			return a
+		}
+	}
+	return nil
+}
+
+func (r testRegister) GetName(a gpchannel.Asset) string {
+	for i, other := range r.assets {
+		if other.Equal(a) {
+			return fmt.Sprint(i)
+		}
+	}
+	return ""
+}
+
+func (r testRegister) GetAllAssets() []gpchannel.Asset {
+	return r.assets
+}
+
+func newTestSUDT(args byte) *asset.Asset {
+	script := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData, Args: []byte{args}}
+	return asset.NewSUDTAsset(asset.NewSUDT(script, 14400000000))
+}
+
+// newTestState creates a channel state in which every asset has the balances
+// bals.
+func newTestState(id gpchannel.ID, assets []gpchannel.Asset, bals ...int64) *gpchannel.State {
+	alloc := gpchannel.NewAllocation(len(bals), assets...)
+	for _, a := range assets {
+		balances := make([]gpchannel.Bal, len(bals))
+		for i, b := range bals {
+			balances[i] = big.NewInt(b)
+		}
+		alloc.SetAssetBalances(a, balances)
+	}
+	return &gpchannel.State{ID: id, Allocation: *alloc, App: gpchannel.NoApp(), Data: gpchannel.NoData()}
+}
+
+// updateEvent returns the event of the wallet service which reports state of
+// the channel between parties.
+func updateEvent(t *testing.T, state *gpchannel.State, parties ...gpwallet.Address) *walletpb.Event {
+	protoState, err := protobuf.FromState(state)
+	require.NoError(t, err)
+	var parts [][]byte
+	for _, addr := range parties {
+		b, err := addr.MarshalBinary()
+		require.NoError(t, err)
+		parts = append(parts, b)
+	}
+	return &walletpb.Event{
+		Type:         walletpb.EventType_EVENT_TYPE_UPDATE,
+		ChannelId:    state.ID[:],
+		State:        protoState,
+		Participants: parts,
+	}
+}
+
+func TestChannelAssets(t *testing.T) {
+	p := newTestClient(t, new(fakeAccount))
+	peer := newTestSigner(t).Address()
+	ckb, sudt := asset.NewCKBytesAsset(), newTestSUDT(1)
+	p.assetRegister = testRegister{assets: []gpchannel.Asset{ckb, sudt}}
+
+	// The channels hold different assets than the register, and the SUDT of
+	// the second one has 2 decimals.
+	cents := newTestSUDT(2)
+	p.decimals = Decimals{cents.SUDT.TypeScript.Hash(): 2}
+	ckbState := newTestState(gpchannel.ID{1}, []gpchannel.Asset{ckb}, 5000000000, 4100000032)
+	sudtState := newTestState(gpchannel.ID{2}, []gpchannel.Asset{ckb, cents}, 10, 20)
+	p.handleEvent(updateEvent(t, ckbState, p.WalletAddress(), peer))
+	p.handleEvent(updateEvent(t, sudtState, peer, p.WalletAddress()))
+
+	for _, state := range []*gpchannel.State{ckbState, sudtState} {
+		assets := p.GetOpenChannelAssets(state.ID)
+		require.Len(t, assets, len(state.Allocation.Assets))
+		for i, a := range assets {
+			require.True(t, a.Equal(state.Allocation.Assets[i]))
+		}
+		ch := p.Channel(state.ID)
+		require.NotPanics(t, func() { FormatState(ch, ch.State(), p.Network, p.assetRegister, p.decimals) })
+	}
+	str := FormatState(p.Channel(sudtState.ID), sudtState, p.Network, p.assetRegister, p.decimals)
+	require.Contains(t, str, "[green]0.1[white]")
+	require.Contains(t, str, "[green]0.0000001[white] 0\n")
+	require.False(t, p.GetOpenChannelAssets(sudtState.ID)[1].Equal(sudt))
+}
+
+func TestPartyIndex(t *testing.T) {
+	ckb := asset.NewCKBytesAsset()
+	tests := []struct {
+		name string
+		// open makes the channel with state known to p, whose wallet service
+		// is account, with the peer peer.
+		open func(t *testing.T, p *WalletClient, account *fakeAccount, state *gpchannel.State, peer gpwallet.Address)
+		idx  gpchannel.Index
+	}{
+		{"proposer", func(t *testing.T, p *WalletClient, _ *fakeAccount, state *gpchannel.State, peer gpwallet.Address) {
+			p.ChannelService = &fakeChannelService{open: func(*proto.ChannelOpenRequest) (*proto.ChannelOpenResponse, error) {
+				p.handleEvent(updateEvent(t, state, p.WalletAddress(), peer))
+				return &proto.ChannelOpenResponse{Msg: &proto.ChannelOpenResponse_ChannelId{ChannelId: state.ID[:]}}, nil
+			}}
+			funding := map[gpchannel.Asset]Funding{ckb: {Own: NewAmount(big.NewInt(5000000000), 8), Peer: NewAmount(new(big.Int), 8)}}
+			require.NoError(t, p.OpenChannel(peer, funding, 60))
+		}, 0},
+		{"responder", func(t *testing.T, p *WalletClient, _ *fakeAccount, state *gpchannel.State, peer gpwallet.Address) {
+			p.handleEvent(updateEvent(t, state, peer, p.WalletAddress()))
+		}, 1},
+		{"restored as responder", func(t *testing.T, p *WalletClient, _ *fakeAccount, state *gpchannel.State, peer gpwallet.Address) {
+			// The channel service reports the restored channels to the wallet
+			// service, which adds the participants which it stored.
+			p.ChannelService = &fakeChannelService{restore: func() {
+				p.handleEvent(updateEvent(t, state, peer, p.WalletAddress()))
+			}}
+			require.NoError(t, p.RestoreChannel())
+		}, 1},
+		{"loaded after restart", func(t *testing.T, p *WalletClient, account *fakeAccount, state *gpchannel.State, peer gpwallet.Address) {
+			e := updateEvent(t, state, peer, p.WalletAddress())
+			account.channels = []*walletpb.Channel{{State: e.State, Participants: e.Participants}}
+			require.NoError(t, p.loadChannels(context.Background()))
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := new(fakeAccount)
+			p := newTestClient(t, account)
+			peer := newTestSigner(t).Address()
+			state := newTestState(gpchannel.ID{1}, []gpchannel.Asset{ckb}, 5000000000, 4100000032)
+			tt.open(t, p, account, state, peer)
+			require.Equal(t, tt.idx, p.Channel(state.ID).Idx())
+
+			// Later updates keep the index, also without participants.
+			next := state.Clone()
+			next.Version++
+			p.handleEvent(updateEvent(t, next))
+			require.Equal(t, next.Version, p.Channel(state.ID).State().Version)
+			require.Equal(t, tt.idx, p.Channel(state.ID).Idx())
+		})
+	}
+
+	// Channels of which we are no participant are ignored.
+	p := newTestClient(t, new(fakeAccount))
+	state := newTestState(gpchannel.ID{2}, []gpchannel.Asset{ckb}, 1, 2)
+	p.handleEvent(updateEvent(t, state, newTestSigner(t).Address(), newTestSigner(t).Address()))
+	require.Nil(t, p.Channel(state.ID))
+}
+
+func TestOpenChannelAwaitsEvent(t *testing.T) {
+	p := newTestClient(t, new(fakeAccount))
+	peer := newTestSigner(t).Address()
+	id := gpchannel.ID{1}
+	ckb := asset.NewCKBytesAsset()
+	initial := newTestState(id, []gpchannel.Asset{ckb}, 5000000000, 4100000032)
+
+	// The initial state is reported only after the channel service responded.
+	p.ChannelService = &fakeChannelService{open: func(*proto.ChannelOpenRequest) (*proto.ChannelOpenResponse, error) {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			p.handleEvent(updateEvent(t, initial, p.WalletAddress(), peer))
+		}()
+		return &proto.ChannelOpenResponse{Msg: &proto.ChannelOpenResponse_ChannelId{ChannelId: id[:]}}, nil
+	}}
+	funding := map[gpchannel.Asset]Funding{ckb: {Own: NewAmount(big.NewInt(5000000000), 8), Peer: NewAmount(new(big.Int), 8)}}
+	require.NoError(t, p.OpenChannel(peer, funding, 60))
+
+	ch := p.Channel(id)
+	require.NotNil(t, ch)
+	require.Equal(t, gpchannel.Index(0), ch.Idx())
+	require.True(t, peer.Equal(ch.parties[1]))
+}
+
+// foreignAsset is an asset of another backend.
+type foreignAsset struct{}
+
+func (foreignAsset) MarshalBinary() ([]byte, error) { return nil, nil }
+func (*foreignAsset) UnmarshalBinary([]byte) error  { return nil }
+func (foreignAsset) Equal(a gpchannel.Asset) bool   { _, ok := a.(*foreignAsset); return ok }
+
+func TestInvalidInput(t *testing.T) {
+	p := newTestClient(t, new(fakeAccount))
+	peer := newTestSigner(t).Address()
+	ckb := asset.NewCKBytesAsset()
+	state := newTestState(gpchannel.ID{1}, []gpchannel.Asset{ckb}, 5000000000, 4100000032)
+	p.handleEvent(updateEvent(t, state, p.WalletAddress(), peer))
+
+	// The channel service is not contacted for invalid requests.
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"open with foreign asset", func() error {
+			funding := map[gpchannel.Asset]Funding{new(foreignAsset): {Own: NewAmount(big.NewInt(1), 0)}}
+			return p.OpenChannel(peer, funding, 60)
+		}},
+		{"pay asset outside channel", func() error {
+			return p.SendPaymentToPeer(state.ID, map[gpchannel.Asset]Amount{newTestSUDT(1): NewAmount(big.NewInt(1), SUDTDecimals)})
+		}},
+		{"pay more than balance", func() error {
+			return p.SendPaymentToPeer(state.ID, map[gpchannel.Asset]Amount{ckb: NewAmount(big.NewInt(5000000001), CKBytesDecimals)})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invalid *InvalidInputError
+			require.ErrorAs(t, tt.call(), &invalid)
+		})
+	}
+	require.Equal(t, state.Allocation.Balances, p.Channel(state.ID).State().Allocation.Balances)
+}
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name     string
+		closeErr error
+		open     bool // Whether the channel is still open after the settle.
+		payout   bool // Whether the payout is known.
+		err      bool
+	}{
+		{"observed by wallet service", nil, true, false, false},
+		{"unknown to wallet service", status.Error(codes.NotFound, "unknown channel"), false, true, false},
+		{"wallet service failure", status.Error(codes.Internal, "store failure"), true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestClient(t, &fakeAccount{closeErr: tt.closeErr})
+			p.ChannelService = new(fakeChannelService)
+			state := newTestState(gpchannel.ID{1}, []gpchannel.Asset{asset.NewCKBytesAsset()}, 5000000000, 4100000032)
+			p.handleEvent(updateEvent(t, state, p.WalletAddress(), newTestSigner(t).Address()))
+
+			err := p.Settle(state.ID)
+			if tt.err {
+				var serviceErr *ServiceError
+				require.ErrorAs(t, err, &serviceErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.open, p.Channel(state.ID) != nil)
+			require.Equal(t, tt.payout, p.hasPayout(state.ID))
+		})
+	}
+}
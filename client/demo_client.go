@@ -0,0 +1,109 @@
+package client
+
+import (
+	"fmt"
+	"log"
+
+	gpchannel "perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	vc "perun.network/perun-demo-tui/client"
+)
+
+// DemoClient adapts a WalletClient to the demo TUI, which only knows about a
+// single channel per client. All channel operations address the most recently
+// updated channel of the underlying WalletClient. Errors are shown to the
+// observers instead of being returned, so the demo keeps running.
+type DemoClient struct {
+	*WalletClient
+}
+
+var _ vc.DemoClient = (*DemoClient)(nil)
+
+// NewDemoClient creates a new DemoClient for the given WalletClient.
+func NewDemoClient(p *WalletClient) *DemoClient {
+	return &DemoClient{WalletClient: p}
+}
+
+// OpenChannel opens a new channel with the specified peer. As the demo has no
+// way to enter the peer's contribution or the challenge duration, the peer
+// deposits the same amounts and the default challenge duration is used.
+func (d *DemoClient) OpenChannel(peer gpwallet.Address, amounts map[gpchannel.Asset]float64) {
+	exact, err := exactAmounts(amounts, d.decimals)
+	if err != nil {
+		d.notifyError(invalidInput("open channel", "%v", err))
+		return
+	}
+	funding := make(map[gpchannel.Asset]Funding, len(exact))
+	for a, amount := range exact {
+		funding[a] = Funding{Own: amount, Peer: amount}
+	}
+	d.notifyError(d.WalletClient.OpenChannel(peer, funding, d.challengeDuration))
+}
+
+// SendPaymentToPeer sends a payment to the peer in the current channel.
+func (d *DemoClient) SendPaymentToPeer(amounts map[gpchannel.Asset]float64) {
+	ch := d.LatestChannel()
+	if ch == nil {
+		return
+	}
+	exact, err := exactAmounts(amounts, d.decimals)
+	if err != nil {
+		d.notifyError(invalidInput("send payment", "%v", err))
+		return
+	}
+	d.notifyError(d.WalletClient.SendPaymentToPeer(ch.ID(), exact))
+}
+
+// exactAmounts converts the amounts entered in the demo, which the TUI only
+// passes as float64, to exact amounts of the respective assets.
+func exactAmounts(amounts map[gpchannel.Asset]float64, d Decimals) (map[gpchannel.Asset]Amount, error) {
+	exact := make(map[gpchannel.Asset]Amount, len(amounts))
+	for a, f := range amounts {
+		decimals, err := d.Of(a)
+		if err != nil {
+			return nil, err
+		}
+		if exact[a], err = amountFromFloat(f, decimals); err != nil {
+			return nil, err
+		}
+	}
+	return exact, nil
+}
+
+// Settle settles the current channel.
+func (d *DemoClient) Settle() {
+	ch := d.LatestChannel()
+	if ch == nil {
+		return
+	}
+	d.notifyError(d.WalletClient.Settle(ch.ID()))
+}
+
+// RestoreChannel restores the client's previously open channels.
+func (d *DemoClient) RestoreChannel() {
+	d.notifyError(d.WalletClient.RestoreChannel())
+}
+
+// GetOpenChannelAssets returns the assets of the current channel.
+func (d *DemoClient) GetOpenChannelAssets() []gpchannel.Asset {
+	ch := d.LatestChannel()
+	if ch == nil {
+		return nil
+	}
+	return ch.Assets()
+}
+
+// notifyError shows the given error to all observers. It does nothing if err
+// is nil.
+func (d *DemoClient) notifyError(err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("%s: %v", d.Name, err)
+	str := fmt.Sprintf("[red]Error[white]: %v", err)
+	d.observerMutex.Lock()
+	defer d.observerMutex.Unlock()
+	for _, o := range d.observers {
+		o.UpdateState(str)
+	}
+}
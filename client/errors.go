@@ -0,0 +1,77 @@
+package client
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RejectedError is returned if the channel service or the peer rejected a
+// request.
+type RejectedError struct {
+	Op     string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s rejected: %s", e.Op, e.Reason)
+}
+
+// TransportError is returned if a request could not be delivered to the
+// channel service or its response could not be received.
+type TransportError struct {
+	Op  string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s: transport failure: %v", e.Op, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// ServiceError is returned if the channel service failed to process a
+// request, e.g., because of an internal error.
+type ServiceError struct {
+	Op  string
+	Err error
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("%s: service failure: %v", e.Op, e.Err)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidInputError is returned if a request was not sent because its
+// arguments are invalid.
+type InvalidInputError struct {
+	Op  string
+	Msg string
+}
+
+func (e *InvalidInputError) Error() string {
+	return fmt.Sprintf("%s: invalid input: %s", e.Op, e.Msg)
+}
+
+func invalidInput(op string, format string, args ...interface{}) error {
+	return &InvalidInputError{Op: op, Msg: fmt.Sprintf(format, args...)}
+}
+
+// callError classifies an error returned by a gRPC call to the channel
+// service. Rejections are not errors but reported in the Rejected message of
+// the responses, see RejectedError. An error status either means that the
+// request or response did not get through, or that the service failed.
+func callError(op string, err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return &TransportError{Op: op, Err: err}
+	default:
+		return &ServiceError{Op: op, Err: err}
+	}
+}
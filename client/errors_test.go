@@ -0,0 +1,25 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCallError(t *testing.T) {
+	var (
+		transport *TransportError
+		service   *ServiceError
+		rejected  *RejectedError
+	)
+	err := callError("op", status.Error(codes.Unavailable, "connection refused"))
+	require.True(t, errors.As(err, &transport))
+
+	// Plain errors of a handler arrive with code Unknown and are no rejections.
+	err = callError("op", status.Error(codes.Unknown, "decoding state: unexpected EOF"))
+	require.True(t, errors.As(err, &service))
+	require.False(t, errors.As(err, &rejected))
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gpchannel "perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+// Payout is the on-chain payout of our final balances in a closed channel.
+type Payout struct {
+	Channel  gpchannel.ID
+	Assets   []gpchannel.Asset
+	Amounts  []*big.Int // Our final balance of each asset.
+	Paid     []*big.Int // Amount of each asset paid by the settlement, nil if unknown.
+	Received bool       // Whether the settlement paid our final balances.
+
+	ch    *PaymentChannel
+	final *gpchannel.State
+}
+
+// Payouts returns the payouts of the channels which were closed since the
+// client started.
+func (p *WalletClient) Payouts() []Payout {
+	p.payoutMutex.Lock()
+	defer p.payoutMutex.Unlock()
+	payouts := make([]Payout, 0, len(p.payouts))
+	for _, payout := range p.payouts {
+		payouts = append(payouts, *payout)
+	}
+	return payouts
+}
+
+// channelClosed handles the close of a channel, which the wallet service
+// reports with its final state once the channel is settled on-chain. It
+// removes the channel, tells the observers and compares the final allocation
+// with the amounts paid by the settlement, which are nil if unknown.
+func (p *WalletClient) channelClosed(id gpchannel.ID, final *gpchannel.Allocation, paid []*big.Int) {
+	ch := p.removeChannel(id)
+	if ch == nil {
+		return
+	}
+	log.Printf("Channel %x of client %s closed", id, p.Name)
+	state := ch.State()
+	state.Allocation = *final
+	payout := &Payout{Channel: id, Assets: ch.Assets(), Paid: paid, Received: paid != nil, ch: ch, final: state}
+	for _, a := range ch.Assets() {
+		amount := final.Balance(ch.idx, a)
+		payout.Amounts = append(payout.Amounts, amount)
+		if paid == nil {
+			continue
+		}
+		i, ok := final.AssetIndex(a)
+		if !ok || int(i) >= len(paid) || !paidEnough(a, amount, paid[i]) {
+			log.Printf("Payout of channel %x to client %s falls short of %v", id, p.Name, amount)
+			payout.Received = false
+		}
+	}
+	p.payoutMutex.Lock()
+	p.payouts[id] = payout
+	p.payoutMutex.Unlock()
+	p.notifyPayout(payout)
+}
+
+// paidEnough returns whether paid covers the expected payout of asset a. The
+// CKBytes paid lack the fee of the settlement if we submitted it.
+func paidEnough(a gpchannel.Asset, expected, paid *big.Int) bool {
+	min := new(big.Int).Set(expected)
+	if ckbAsset, ok := a.(*asset.Asset); ok && ckbAsset.IsCKBytes {
+		min.Sub(min, new(big.Int).SetUint64(wallet_service.MaxTransactionFee))
+	}
+	return paid.Cmp(min) >= 0
+}
+
+// closeChannel tells the wallet service that we settled the channel ch. The
+// wallet service reports the close once it observes the settlement on-chain.
+// If it does not know the channel, e.g., because it was restored by the
+// channel service, the latest state of ch is final and its payout unknown.
+func (p *WalletClient) closeChannel(ch *PaymentChannel) error {
+	err := p.wallet.CloseChannel(context.Background(), ch.ID())
+	switch {
+	case err == nil:
+		p.notifyProgress(ch, "[yellow]Settling[white]: waiting for the settlement on-chain")
+		return nil
+	case status.Code(err) == codes.NotFound:
+		log.Printf("Channel %x is unknown to the wallet service: %v", ch.ID(), err)
+		state := ch.State()
+		p.channelClosed(ch.ID(), &state.Allocation, nil)
+		return nil
+	default:
+		return err
+	}
+}
+
+// hasPayout returns whether the channel with the given ID was closed.
+func (p *WalletClient) hasPayout(id gpchannel.ID) bool {
+	p.payoutMutex.Lock()
+	defer p.payoutMutex.Unlock()
+	_, ok := p.payouts[id]
+	return ok
+}
+
+// notifyPayout shows the final state of a closed channel and the state of its
+// payout to all observers.
+func (p *WalletClient) notifyPayout(payout *Payout) {
+	status := "[green]received[white]"
+	switch {
+	case payout.Paid == nil:
+		status = "[yellow]unknown[white]"
+	case !payout.Received:
+		status = "[red]short[white]"
+	}
+	str := FormatState(payout.ch, payout.final, p.Network, p.assetRegister, p.decimals) +
+		fmt.Sprintf("\n[red]Channel closed[white], payout %s", status)
+	p.observerMutex.Lock()
+	defer p.observerMutex.Unlock()
+	for _, o := range p.observers {
+		o.UpdateState(str)
+	}
+}
@@ -1,22 +1,31 @@
 package client
 
 import (
-	"math/big"
+	gpchannel "perun.network/go-perun/channel"
 )
 
-// CKByteToShannon converts a given amount in CKByte to Shannon.
-func CKByteToShannon(ckbyteAmount *big.Float) (shannonAmount *big.Int) {
-	shannonPerCKByte := new(big.Int).Exp(big.NewInt(10), big.NewInt(8), nil)
-	shannonPerCKByteFloat := new(big.Float).SetInt(shannonPerCKByte)
-	shannonAmountFloat := new(big.Float).Mul(ckbyteAmount, shannonPerCKByteFloat)
-	shannonAmount, _ = shannonAmountFloat.Int(nil)
-	return shannonAmount
+// isEmpty returns true iff no participant holds any funds in the allocation.
+func isEmpty(alloc *gpchannel.Allocation) bool {
+	for _, sum := range alloc.Sum() {
+		if sum.Sign() != 0 {
+			return false
+		}
+	}
+	return true
 }
 
-// ShannonToCKByte converts a given amount in Shannon to CKByte.
-func ShannonToCKByte(shannonAmount *big.Int) *big.Float {
-	shannonPerCKByte := new(big.Int).Exp(big.NewInt(10), big.NewInt(8), nil)
-	shannonPerCKByteFloat := new(big.Float).SetInt(shannonPerCKByte)
-	shannonAmountFloat := new(big.Float).SetInt(shannonAmount)
-	return new(big.Float).Quo(shannonAmountFloat, shannonPerCKByteFloat)
+// phaseDescription describes what happens in the given phase of a force close.
+func phaseDescription(phase gpchannel.Phase) string {
+	switch phase {
+	case gpchannel.Registering:
+		return "registering the latest state on-chain"
+	case gpchannel.Registered:
+		return "waiting for the challenge period to elapse"
+	case gpchannel.Withdrawing:
+		return "withdrawing the funds"
+	case gpchannel.Withdrawn:
+		return "funds withdrawn, channel closed"
+	default:
+		return phase.String()
+	}
 }
@@ -0,0 +1,421 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PathEnv is the environment variable which overrides the location of the
+	// configuration file.
+	PathEnv = "PERUN_DEMO_CONFIG"
+	// envPrefix is the prefix of all environment variables which override
+	// configuration values.
+	envPrefix = "PERUN_DEMO_"
+)
+
+// Config is the configuration shared by the demo client and the channel
+// service. Relative paths are resolved against the directory of the
+// configuration file.
+type Config struct {
+	NodeURL       string `yaml:"node_url"`
+	Network       string `yaml:"network"`
+	DeploymentDir string `yaml:"deployment_dir"`
+	// ChallengeDuration is the default challenge duration of new channels in
+	// seconds. Channels may only be opened or accepted with a challenge
+	// duration between MinChallengeDuration and MaxChallengeDuration, where a
+	// zero MaxChallengeDuration means that there is no upper bound.
+	ChallengeDuration    uint64 `yaml:"challenge_duration"`
+	MinChallengeDuration uint64 `yaml:"min_challenge_duration"`
+	MaxChallengeDuration uint64 `yaml:"max_challenge_duration"`
+	// ApprovalTimeout is the time in seconds in which incoming channel
+	// proposals have to be approved. Zero selects the default of one minute.
+	ApprovalTimeout uint64 `yaml:"approval_timeout"`
+	// ReadyTimeout is the time in seconds in which the services have to
+	// become ready after the start. Zero selects the default of one minute.
+	ReadyTimeout uint64 `yaml:"ready_timeout"`
+	// SUDTDecimals is the number of decimals of the SUDT of the deployment,
+	// with which its amounts are entered and shown.
+	SUDTDecimals uint8         `yaml:"sudt_decimals"`
+	Participants []Participant `yaml:"participants"`
+	// Watchtower is the configuration of the optional watchtower service.
+	Watchtower *Watchtower `yaml:"watchtower"`
+	// WalletAccounts are optional accounts which are hosted by a wallet
+	// service without a demo client.
+	WalletAccounts *WalletAccounts `yaml:"wallet_accounts"`
+	// TLS enables mutual TLS between the demo clients, the channel service
+	// and the wallet services if it is set.
+	TLS *TLS `yaml:"tls"`
+}
+
+// Participant is the configuration of one participant of the demo.
+type Participant struct {
+	Name           string `yaml:"name"`
+	KeyFile        string `yaml:"key_file"`
+	WalletService  string `yaml:"wallet_service"`
+	ChannelService string `yaml:"channel_service"`
+	DBDir          string `yaml:"db_dir"`
+	// KeyFormat is the format of KeyFile: "keystore" (the default) for an
+	// encrypted keystore, "ckb-cli" for a JSON keystore of ckb-cli, "mnemonic"
+	// for a BIP39 mnemonic or "plain" for an unencrypted key, which is only
+	// meant for development.
+	KeyFormat string `yaml:"key_format"`
+	// PassphraseFile contains the passphrase of the keystore. The environment
+	// variable returned by PassphraseEnv takes precedence, and the passphrase
+	// is asked on the terminal if neither is set.
+	PassphraseFile string `yaml:"passphrase_file"`
+	// Signer is the unix socket of the participant's signer daemon. If it is
+	// set, only the daemon unlocks the key and the wallet service asks it for
+	// all signatures. Otherwise, the wallet service holds the key itself.
+	Signer string `yaml:"signer"`
+	// WalletDBDir is the database of the participant's wallet service. If it
+	// is empty, the wallet service does not persist its data.
+	WalletDBDir string `yaml:"wallet_db_dir"`
+	// ApprovalRules is the optional rules file which decides on incoming
+	// channel proposals. Without rules, all valid proposals are accepted.
+	ApprovalRules string `yaml:"approval_rules"`
+	// AuditLog is the tamper-evident log of everything the participant's
+	// wallet service signs. If it is empty, no audit log is written.
+	AuditLog string `yaml:"audit_log"`
+}
+
+// Watchtower is the configuration of the watchtower service.
+type Watchtower struct {
+	Address string `yaml:"address"`
+	// KeyFile is the key of the account which pays the transaction fees of
+	// disputes. KeyFormat and PassphraseFile are as for participants.
+	KeyFile        string `yaml:"key_file"`
+	KeyFormat      string `yaml:"key_format"`
+	PassphraseFile string `yaml:"passphrase_file"`
+	DBDir          string `yaml:"db_dir"`
+}
+
+// WalletAccounts is the configuration of the accounts which a wallet service
+// loads from a keystore directory.
+type WalletAccounts struct {
+	// Dir contains one encrypted keystore "<name>.json" per account.
+	// Keystores which are added to it later on are loaded without a restart.
+	Dir string `yaml:"dir"`
+	// WalletService is the address of the wallet service which hosts the
+	// accounts. Participants may use the same address.
+	WalletService string `yaml:"wallet_service"`
+	// PassphraseFile contains the passphrase of the keystores in Dir which
+	// have no passphrase file of their own, see PassphraseFileOf. The
+	// environment variable PassphraseEnv("wallet_accounts") takes precedence.
+	PassphraseFile string `yaml:"passphrase_file"`
+	// DBDir contains the database of every account. If it is empty, the
+	// wallet services do not persist their data.
+	DBDir string `yaml:"db_dir"`
+}
+
+// PassphraseFileOf returns the file "<name>.passphrase" next to the keystore
+// of the account with the given name, and own is true, if the keystore has
+// such a passphrase file. Otherwise, it returns the shared PassphraseFile.
+func (a *WalletAccounts) PassphraseFileOf(name string) (file string, own bool) {
+	file = filepath.Join(a.Dir, name+".passphrase")
+	if _, err := os.Stat(file); err == nil {
+		return file, true
+	}
+	return a.PassphraseFile, false
+}
+
+// TLS is the configuration of mutual TLS.
+type TLS struct {
+	// CertDir contains the CA and the certificates of all services, as
+	// generated by the certs command. See package mtls for their names.
+	CertDir string `yaml:"cert_dir"`
+}
+
+// PassphraseEnv returns the environment variable which holds the passphrase
+// of the keystore of the participant with the given name, or of the
+// watchtower if name is "watchtower".
+func PassphraseEnv(name string) string {
+	return envPrefix + strings.ToUpper(name) + "_PASSPHRASE"
+}
+
+// Load reads the configuration from the file at path, or from the file given
+// by the PERUN_DEMO_CONFIG environment variable if it is set. Afterwards,
+// values are overridden by the environment (see ApplyEnv) and relative paths
+// are resolved.
+func Load(path string) (*Config, error) {
+	if p, ok := os.LookupEnv(PathEnv); ok {
+		path = p
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	cfg.resolvePaths(filepath.Dir(path))
+	return cfg, cfg.Validate()
+}
+
+// Parse parses a YAML encoded configuration.
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// ApplyEnv overrides configuration values with the environment variables
+// returned by lookup:
+//
+//	PERUN_DEMO_NODE_URL, PERUN_DEMO_NETWORK, PERUN_DEMO_DEPLOYMENT_DIR,
+//	PERUN_DEMO_CHALLENGE_DURATION, PERUN_DEMO_MIN_CHALLENGE_DURATION,
+//	PERUN_DEMO_MAX_CHALLENGE_DURATION, PERUN_DEMO_APPROVAL_TIMEOUT,
+//	PERUN_DEMO_READY_TIMEOUT, PERUN_DEMO_SUDT_DECIMALS
+//
+// and for every participant, e.g., Alice:
+//
+//	PERUN_DEMO_ALICE_KEY_FILE, PERUN_DEMO_ALICE_KEY_FORMAT,
+//	PERUN_DEMO_ALICE_PASSPHRASE_FILE, PERUN_DEMO_ALICE_SIGNER,
+//	PERUN_DEMO_ALICE_WALLET_SERVICE, PERUN_DEMO_ALICE_CHANNEL_SERVICE,
+//	PERUN_DEMO_ALICE_DB_DIR, PERUN_DEMO_ALICE_WALLET_DB_DIR,
+//	PERUN_DEMO_ALICE_APPROVAL_RULES, PERUN_DEMO_ALICE_AUDIT_LOG
+//
+// and for the watchtower, if it is configured:
+//
+//	PERUN_DEMO_WATCHTOWER_ADDRESS, PERUN_DEMO_WATCHTOWER_KEY_FILE,
+//	PERUN_DEMO_WATCHTOWER_KEY_FORMAT, PERUN_DEMO_WATCHTOWER_PASSPHRASE_FILE,
+//	PERUN_DEMO_WATCHTOWER_DB_DIR
+//
+// and for the wallet accounts, if they are configured:
+//
+//	PERUN_DEMO_WALLET_ACCOUNTS_DIR, PERUN_DEMO_WALLET_ACCOUNTS_WALLET_SERVICE,
+//	PERUN_DEMO_WALLET_ACCOUNTS_PASSPHRASE_FILE, PERUN_DEMO_WALLET_ACCOUNTS_DB_DIR
+//
+// and for mutual TLS, if it is configured:
+//
+//	PERUN_DEMO_TLS_CERT_DIR
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	override := func(name string, v *string) {
+		if s, ok := lookup(envPrefix + name); ok {
+			*v = s
+		}
+	}
+	override("NODE_URL", &c.NodeURL)
+	override("NETWORK", &c.Network)
+	override("DEPLOYMENT_DIR", &c.DeploymentDir)
+	overrideUint := func(name string, v *uint64) error {
+		s, ok := lookup(envPrefix + name)
+		if !ok {
+			return nil
+		}
+		d, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing %s%s: %w", envPrefix, name, err)
+		}
+		*v = d
+		return nil
+	}
+	if err := overrideUint("CHALLENGE_DURATION", &c.ChallengeDuration); err != nil {
+		return err
+	}
+	if err := overrideUint("MIN_CHALLENGE_DURATION", &c.MinChallengeDuration); err != nil {
+		return err
+	}
+	if err := overrideUint("MAX_CHALLENGE_DURATION", &c.MaxChallengeDuration); err != nil {
+		return err
+	}
+	if err := overrideUint("APPROVAL_TIMEOUT", &c.ApprovalTimeout); err != nil {
+		return err
+	}
+	if err := overrideUint("READY_TIMEOUT", &c.ReadyTimeout); err != nil {
+		return err
+	}
+	if s, ok := lookup(envPrefix + "SUDT_DECIMALS"); ok {
+		d, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			return fmt.Errorf("parsing %sSUDT_DECIMALS: %w", envPrefix, err)
+		}
+		c.SUDTDecimals = uint8(d)
+	}
+	for i := range c.Participants {
+		p := &c.Participants[i]
+		name := strings.ToUpper(p.Name) + "_"
+		override(name+"KEY_FILE", &p.KeyFile)
+		override(name+"KEY_FORMAT", &p.KeyFormat)
+		override(name+"PASSPHRASE_FILE", &p.PassphraseFile)
+		override(name+"SIGNER", &p.Signer)
+		override(name+"WALLET_SERVICE", &p.WalletService)
+		override(name+"CHANNEL_SERVICE", &p.ChannelService)
+		override(name+"DB_DIR", &p.DBDir)
+		override(name+"WALLET_DB_DIR", &p.WalletDBDir)
+		override(name+"APPROVAL_RULES", &p.ApprovalRules)
+		override(name+"AUDIT_LOG", &p.AuditLog)
+	}
+	if w := c.Watchtower; w != nil {
+		override("WATCHTOWER_ADDRESS", &w.Address)
+		override("WATCHTOWER_KEY_FILE", &w.KeyFile)
+		override("WATCHTOWER_KEY_FORMAT", &w.KeyFormat)
+		override("WATCHTOWER_PASSPHRASE_FILE", &w.PassphraseFile)
+		override("WATCHTOWER_DB_DIR", &w.DBDir)
+	}
+	if a := c.WalletAccounts; a != nil {
+		override("WALLET_ACCOUNTS_DIR", &a.Dir)
+		override("WALLET_ACCOUNTS_WALLET_SERVICE", &a.WalletService)
+		override("WALLET_ACCOUNTS_PASSPHRASE_FILE", &a.PassphraseFile)
+		override("WALLET_ACCOUNTS_DB_DIR", &a.DBDir)
+	}
+	if t := c.TLS; t != nil {
+		override("TLS_CERT_DIR", &t.CertDir)
+	}
+	return nil
+}
+
+// Validate checks that all required values are set.
+func (c *Config) Validate() error {
+	if c.NodeURL == "" {
+		return errors.New("missing node_url")
+	}
+	if _, err := c.CKBNetwork(); err != nil {
+		return err
+	}
+	if c.DeploymentDir == "" {
+		return errors.New("missing deployment_dir")
+	}
+	if c.MaxChallengeDuration != 0 && c.MinChallengeDuration > c.MaxChallengeDuration {
+		return errors.New("min_challenge_duration exceeds max_challenge_duration")
+	}
+	if c.ChallengeDuration == 0 || c.ChallengeDuration < c.MinChallengeDuration ||
+		(c.MaxChallengeDuration != 0 && c.ChallengeDuration > c.MaxChallengeDuration) {
+		return fmt.Errorf("challenge_duration %d is outside of the bounds [%d, %d]", c.ChallengeDuration, c.MinChallengeDuration, c.MaxChallengeDuration)
+	}
+	if len(c.Participants) == 0 {
+		return errors.New("no participants configured")
+	}
+	names := make(map[string]bool)
+	for i, p := range c.Participants {
+		if p.Name == "" {
+			return fmt.Errorf("participant %d: missing name", i)
+		}
+		if names[strings.ToUpper(p.Name)] {
+			return fmt.Errorf("participant %s: duplicate name", p.Name)
+		}
+		names[strings.ToUpper(p.Name)] = true
+		if p.KeyFile == "" || p.WalletService == "" || p.ChannelService == "" || p.DBDir == "" {
+			return fmt.Errorf("participant %s: key_file, wallet_service, channel_service and db_dir are required", p.Name)
+		}
+		if err := validateKeyFormat(p.KeyFormat); err != nil {
+			return fmt.Errorf("participant %s: %w", p.Name, err)
+		}
+	}
+	if w := c.Watchtower; w != nil {
+		if w.Address == "" || w.KeyFile == "" || w.DBDir == "" {
+			return errors.New("watchtower: address, key_file and db_dir are required")
+		}
+		if err := validateKeyFormat(w.KeyFormat); err != nil {
+			return fmt.Errorf("watchtower: %w", err)
+		}
+	}
+	if a := c.WalletAccounts; a != nil && (a.Dir == "" || a.WalletService == "") {
+		return errors.New("wallet_accounts: dir and wallet_service are required")
+	}
+	if t := c.TLS; t != nil && t.CertDir == "" {
+		return errors.New("tls: cert_dir is required")
+	}
+	return nil
+}
+
+func validateKeyFormat(format string) error {
+	switch format {
+	case "", "keystore", "ckb-cli", "mnemonic", "plain":
+		return nil
+	default:
+		return fmt.Errorf("unknown key_format %q", format)
+	}
+}
+
+// CKBNetwork returns the configured CKB network.
+func (c *Config) CKBNetwork() (types.Network, error) {
+	switch c.Network {
+	case "testnet", "":
+		return types.NetworkTest, nil
+	case "mainnet":
+		return types.NetworkMain, nil
+	default:
+		return 0, fmt.Errorf("unknown network %q", c.Network)
+	}
+}
+
+// Participant returns the configuration of the participant with the given
+// name. Names are compared case-insensitively.
+func (c *Config) Participant(name string) (Participant, bool) {
+	for _, p := range c.Participants {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Participant{}, false
+}
+
+// ReadyWait returns the time in which the services have to become ready.
+func (c *Config) ReadyWait() time.Duration {
+	if c.ReadyTimeout == 0 {
+		return time.Minute
+	}
+	return time.Duration(c.ReadyTimeout) * time.Second
+}
+
+// MigrationDir returns the directory containing the contract migration.
+func (c *Config) MigrationDir() string {
+	return filepath.Join(c.DeploymentDir, "contracts", "migrations", "dev")
+}
+
+// SystemScriptsDir returns the directory containing the system scripts.
+func (c *Config) SystemScriptsDir() string {
+	return filepath.Join(c.DeploymentDir, "system_scripts")
+}
+
+// SUDTOwnerLockArgFile returns the file containing the SUDT owner lock arg.
+func (c *Config) SUDTOwnerLockArgFile() string {
+	return filepath.Join(c.DeploymentDir, "accounts", "sudt-owner-lock-hash.txt")
+}
+
+// resolvePaths makes all relative paths relative to dir.
+func (c *Config) resolvePaths(dir string) {
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	resolve(&c.DeploymentDir)
+	for i := range c.Participants {
+		resolve(&c.Participants[i].KeyFile)
+		resolve(&c.Participants[i].PassphraseFile)
+		resolve(&c.Participants[i].Signer)
+		resolve(&c.Participants[i].DBDir)
+		resolve(&c.Participants[i].WalletDBDir)
+		resolve(&c.Participants[i].ApprovalRules)
+		resolve(&c.Participants[i].AuditLog)
+	}
+	if c.Watchtower != nil {
+		resolve(&c.Watchtower.KeyFile)
+		resolve(&c.Watchtower.PassphraseFile)
+		resolve(&c.Watchtower.DBDir)
+	}
+	if c.WalletAccounts != nil {
+		resolve(&c.WalletAccounts.Dir)
+		resolve(&c.WalletAccounts.PassphraseFile)
+		resolve(&c.WalletAccounts.DBDir)
+	}
+	if c.TLS != nil {
+		resolve(&c.TLS.CertDir)
+	}
+}
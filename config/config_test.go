@@ -0,0 +1,114 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/perun-nervos-demo/config"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(configCase), 0600))
+
+	t.Setenv("PERUN_DEMO_NODE_URL", "http://node:8114")
+	t.Setenv("PERUN_DEMO_BOB_WALLET_SERVICE", "bob-host:50052")
+	t.Setenv("PERUN_DEMO_CHALLENGE_DURATION", "60")
+	t.Setenv("PERUN_DEMO_SUDT_DECIMALS", "6")
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	require.Equal(t, "http://node:8114", cfg.NodeURL)
+	require.Equal(t, uint64(60), cfg.ChallengeDuration)
+	require.Equal(t, uint8(6), cfg.SUDTDecimals)
+	require.Equal(t, filepath.Join(dir, "devnet"), cfg.DeploymentDir)
+
+	alice, ok := cfg.Participant("alice")
+	require.True(t, ok)
+	require.Equal(t, filepath.Join(dir, "devnet/accounts/alice.pk"), alice.KeyFile)
+	require.Equal(t, "localhost:50051", alice.WalletService)
+	bob, ok := cfg.Participant("Bob")
+	require.True(t, ok)
+	require.Equal(t, "bob-host:50052", bob.WalletService)
+	require.Equal(t, "/var/lib/bob-db", bob.DBDir)
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := config.Parse([]byte(configCase))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	cfg.Network = "devnet"
+	require.Error(t, cfg.Validate())
+
+	cfg, err = config.Parse([]byte(configCase))
+	require.NoError(t, err)
+	cfg.Participants[1].KeyFile = ""
+	require.Error(t, cfg.Validate())
+
+	cfg, err = config.Parse([]byte(configCase))
+	require.NoError(t, err)
+	cfg.Participants[1].Name = "alice"
+	require.Error(t, cfg.Validate())
+	cfg.Participants[1].Name = ""
+	require.Error(t, cfg.Validate())
+
+	// Any number of participants can be configured.
+	cfg, err = config.Parse([]byte(configCase))
+	require.NoError(t, err)
+	carol := cfg.Participants[1]
+	carol.Name = "Carol"
+	cfg.Participants = append(cfg.Participants, carol)
+	require.NoError(t, cfg.Validate())
+	cfg.Participants = nil
+	require.Error(t, cfg.Validate())
+
+	cfg, err = config.Parse([]byte(configCase))
+	require.NoError(t, err)
+	cfg.Participants[0].KeyFormat = "hex"
+	require.Error(t, cfg.Validate())
+
+	cfg, err = config.Parse([]byte(configCase))
+	require.NoError(t, err)
+	cfg.MinChallengeDuration = 20
+	require.Error(t, cfg.Validate())
+	cfg.MinChallengeDuration, cfg.MaxChallengeDuration = 0, 5
+	require.Error(t, cfg.Validate())
+	cfg.ChallengeDuration = 0
+	cfg.MaxChallengeDuration = 0
+	require.Error(t, cfg.Validate())
+}
+
+var configCase = `
+node_url: http://localhost:8114
+network: testnet
+deployment_dir: devnet
+challenge_duration: 10
+participants:
+  - name: Alice
+    key_file: devnet/accounts/alice.pk
+    wallet_service: localhost:50051
+    channel_service: localhost:4321
+    db_dir: alice-db
+  - name: Bob
+    key_file: devnet/accounts/bob.pk
+    wallet_service: localhost:50052
+    channel_service: localhost:4322
+    db_dir: /var/lib/bob-db
+`
+
+func TestPassphraseFileOf(t *testing.T) {
+	dir := t.TempDir()
+	wa := &config.WalletAccounts{Dir: dir, PassphraseFile: filepath.Join(dir, "shared")}
+	file, own := wa.PassphraseFileOf("carol")
+	require.False(t, own)
+	require.Equal(t, wa.PassphraseFile, file)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "carol.passphrase"), []byte("secret"), 0600))
+	file, own = wa.PassphraseFileOf("carol")
+	require.True(t, own)
+	require.Equal(t, filepath.Join(dir, "carol.passphrase"), file)
+}
@@ -3,6 +3,7 @@ package deployment
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -157,3 +158,16 @@ func GetDeployment(migrationDir, systemScriptsDir, sudtOwnerLockArg string) (bac
 	}
 	return migration.MakeDeployment(ss, sudtOwnerLockArg)
 }
+
+// GetSUDTOwnerLockArg reads the lock arg of the SUDT owner from the given file.
+func GetSUDTOwnerLockArg(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading sudt owner lock arg from file: %w", err)
+	}
+	sudtOwnerLockArg := string(b)
+	if sudtOwnerLockArg == "" {
+		return "", errors.New("sudt owner lock arg not found in file")
+	}
+	return sudtOwnerLockArg, nil
+}
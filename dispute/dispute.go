@@ -0,0 +1,105 @@
+// Package dispute defines the dispute service, which the channel service of
+// this demo offers next to the channel service API. It allows to force-close
+// a channel on-chain if the peer is unresponsive.
+//
+// The service reuses the protobuf messages of the channel service API, so that
+// no additional code generation is needed:
+//
+//	service DisputeService {
+//	  rpc ForceClose(ChannelCloseRequest) returns (stream google.protobuf.UInt32Value);
+//	}
+//
+// ForceClose reports every phase (see channel.Phase) which the channel enters
+// while it is registered on-chain, the challenge period elapses and the funds
+// are withdrawn. The stream ends after the phase channel.Withdrawn.
+package dispute
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/go-perun/channel"
+)
+
+const (
+	serviceName      = "perun_nervos_demo.DisputeService"
+	forceCloseMethod = "/" + serviceName + "/ForceClose"
+)
+
+// Server is the server API of the dispute service.
+type Server interface {
+	// ForceClose force-closes the requested channel and reports its phases to
+	// report.
+	ForceClose(ctx context.Context, req *proto.ChannelCloseRequest, report func(channel.Phase) error) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*Server)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "ForceClose",
+		Handler:       forceCloseHandler,
+		ServerStreams: true,
+	}},
+}
+
+// RegisterServer registers the dispute service srv at s.
+func RegisterServer(s *grpc.Server, srv Server) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+func forceCloseHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(proto.ChannelCloseRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(Server).ForceClose(stream.Context(), req, func(phase channel.Phase) error {
+		return stream.SendMsg(wrapperspb.UInt32(uint32(phase)))
+	})
+}
+
+// Client is the client of the dispute service.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient creates a client of the dispute service served at cc.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// ForceClose force-closes the channel with the given ID. It calls progress
+// with every phase which the channel enters and returns after the funds were
+// withdrawn.
+func (c *Client) ForceClose(ctx context.Context, id channel.ID, progress func(channel.Phase)) error {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], forceCloseMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&proto.ChannelCloseRequest{ChannelId: id[:]}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	withdrawn := false
+	for {
+		phase := new(wrapperspb.UInt32Value)
+		err := stream.RecvMsg(phase)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		withdrawn = channel.Phase(phase.Value) == channel.Withdrawn
+		progress(channel.Phase(phase.Value))
+	}
+	if !withdrawn {
+		return errors.New("force close ended before the funds were withdrawn")
+	}
+	return nil
+}
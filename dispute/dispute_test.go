@@ -0,0 +1,74 @@
+package dispute
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/go-perun/channel"
+)
+
+// fakeServer reports phases for the requested channel and returns err.
+type fakeServer struct {
+	phases []channel.Phase
+	err    error
+	id     chan []byte
+}
+
+func (s *fakeServer) ForceClose(_ context.Context, req *proto.ChannelCloseRequest, report func(channel.Phase) error) error {
+	s.id <- req.GetChannelId()
+	for _, phase := range s.phases {
+		if err := report(phase); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func TestForceClose(t *testing.T) {
+	tests := []struct {
+		name   string
+		phases []channel.Phase
+		err    error
+		code   codes.Code // Status code of the returned error, OK for none.
+	}{
+		{"withdrawn", []channel.Phase{channel.Acting, channel.Registered, channel.Withdrawn}, nil, codes.OK},
+		{"server failure", []channel.Phase{channel.Acting}, errors.New("settling failed"), codes.Unknown},
+		{"ended early", []channel.Phase{channel.Acting, channel.Registered}, nil, codes.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &fakeServer{phases: tt.phases, err: tt.err, id: make(chan []byte, 1)}
+			s := grpc.NewServer()
+			RegisterServer(s, srv)
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go s.Serve(lis)
+			defer s.Stop()
+			conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			id := channel.ID{1, 2, 3}
+			var phases []channel.Phase
+			err = NewClient(conn).ForceClose(context.Background(), id, func(phase channel.Phase) {
+				phases = append(phases, phase)
+			})
+			require.Equal(t, id[:], <-srv.id)
+			require.Equal(t, tt.phases, phases)
+			if tt.code == codes.OK {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Equal(t, tt.code, status.Code(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/approval"
+	"perun.network/perun-nervos-demo/client"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/keystore"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+// Exit codes of the headless command mode.
+const (
+	exitOK       = 0 // The command succeeded.
+	exitFailure  = 1 // The command failed, e.g., because a service is unreachable.
+	exitUsage    = 2 // The command line or the input of the command is invalid.
+	exitRejected = 3 // The channel service or the peer rejected the request.
+)
+
+const usage = `Usage: perun-nervos-demo <command> [flags]
+
+Without a command, the interactive demo is started. Commands:
+  open     open a channel with a peer
+  pay      send a payment in a channel
+  settle   settle a channel
+  force-close
+           close a channel on-chain without the peer's cooperation
+  restore  restore the channels from the channel service's database
+  balance  print the on-chain balance
+  status   print the open channels
+  history  print all states of a channel which the wallet service kept
+  serve    keep the wallet service running to respond to the peer; with
+           -prompt, channel proposals have to be approved on stdin
+  key      manage encrypted keystores, see "key -h"
+  audit    verify the audit log of a wallet service, see "audit -h"
+  certs    generate the certificates for mutual TLS, see "certs -h"
+
+Every command accepts -as <name> to select the participant (default: Alice).
+Results are printed as JSON to stdout, logs are written to demo.log.
+`
+
+// command is a headless command acting on the client of one participant.
+type command struct {
+	flags *flag.FlagSet
+	// syncChannels is set if the command needs the channels which the channel
+	// service currently holds for the participant.
+	syncChannels bool
+	run          func(c *client.WalletClient, out *json.Encoder) error
+}
+
+// runCommand runs the headless command given by args and returns the exit
+// code of the process.
+func runCommand(cfg *config.Config, args []string) int {
+	var (
+		name      string
+		peerName  string
+		chID      string
+		assetName string
+		amount    string
+		peerAmt   string
+		challenge uint64
+		prompt    bool
+		ws        *wallet_service.MyWalletService // Wallet service of the participant.
+	)
+	asFlag := func(fs *flag.FlagSet) {
+		fs.StringVar(&name, "as", cfg.Participants[0].Name, "name of the participant to act as")
+	}
+	channelFlag := func(fs *flag.FlagSet) {
+		fs.StringVar(&chID, "channel", "", "hex encoded channel ID (default: the only open channel)")
+	}
+	assetFlag := func(fs *flag.FlagSet) {
+		fs.StringVar(&assetName, "asset", "CKBytes", "name of the asset")
+	}
+
+	assetRegister, err := newDemoAssetRegister()
+	if err != nil {
+		log.Printf("creating asset register: %v", err)
+		return exitFailure
+	}
+
+	commands := map[string]command{
+		"open": {
+			flags: flagSet("open", asFlag, assetFlag, func(fs *flag.FlagSet) {
+				fs.StringVar(&peerName, "peer", "", "name of the peer")
+				fs.StringVar(&amount, "amount", "0", "own contribution, e.g. 400 or 0.5")
+				fs.StringVar(&peerAmt, "peer-amount", "0", "peer's contribution")
+				fs.Uint64Var(&challenge, "challenge-duration", cfg.ChallengeDuration, "on-chain challenge duration in seconds")
+			}),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				a := assetRegister.GetAsset(assetName)
+				if a == nil {
+					return fmt.Errorf("%w: unknown asset %q", errUsage, assetName)
+				}
+				peer, err := participantAddress(cfg, peerName)
+				if err != nil {
+					return err
+				}
+				own, err := c.Decimals().Parse(a, amount)
+				if err != nil {
+					return fmt.Errorf("%w: -amount: %v", errUsage, err)
+				}
+				peerOwn, err := c.Decimals().Parse(a, peerAmt)
+				if err != nil {
+					return fmt.Errorf("%w: -peer-amount: %v", errUsage, err)
+				}
+				funding := map[channel.Asset]client.Funding{a: {Own: own, Peer: peerOwn}}
+				if err := c.OpenChannel(peer, funding, challenge); err != nil {
+					return err
+				}
+				return out.Encode(channelInfos(c))
+			},
+		},
+		"pay": {
+			syncChannels: true,
+			flags: flagSet("pay", asFlag, channelFlag, assetFlag, func(fs *flag.FlagSet) {
+				fs.StringVar(&amount, "amount", "0", "amount to send to the peer, e.g. 10 or 0.5")
+			}),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				a := assetRegister.GetAsset(assetName)
+				if a == nil {
+					return fmt.Errorf("%w: unknown asset %q", errUsage, assetName)
+				}
+				ch, err := selectChannel(c, chID)
+				if err != nil {
+					return err
+				}
+				pay, err := c.Decimals().Parse(a, amount)
+				if err != nil {
+					return fmt.Errorf("%w: -amount: %v", errUsage, err)
+				}
+				if err := c.SendPaymentToPeer(ch.ID(), map[channel.Asset]client.Amount{a: pay}); err != nil {
+					return err
+				}
+				return out.Encode(newChannelInfo(c.Channel(ch.ID())))
+			},
+		},
+		"settle": {
+			syncChannels: true,
+			flags:        flagSet("settle", asFlag, channelFlag),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				ch, err := selectChannel(c, chID)
+				if err != nil {
+					return err
+				}
+				if err := c.Settle(ch.ID()); err != nil {
+					return err
+				}
+				return out.Encode(map[string]string{"settled": hex.EncodeToString(ch.State().ID[:])})
+			},
+		},
+		"force-close": {
+			syncChannels: true,
+			flags:        flagSet("force-close", asFlag, channelFlag),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				ch, err := selectChannel(c, chID)
+				if err != nil {
+					return err
+				}
+				progress := func(phase channel.Phase) {
+					_ = out.Encode(map[string]string{"phase": phase.String()})
+				}
+				if err := c.ForceClose(ch.ID(), progress); err != nil {
+					return err
+				}
+				return out.Encode(map[string]string{"force_closed": hex.EncodeToString(ch.State().ID[:])})
+			},
+		},
+		"restore": {
+			flags: flagSet("restore", asFlag),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				if err := c.RestoreChannel(); err != nil {
+					return err
+				}
+				return out.Encode(channelInfos(c))
+			},
+		},
+		"balance": {
+			flags: flagSet("balance", asFlag),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				ckb, sudt, err := c.FetchBalances(ctx)
+				if err != nil {
+					return &client.TransportError{Op: "balance", Err: err}
+				}
+				return out.Encode(map[string]string{
+					"address": c.DisplayAddress(),
+					"ckb":     ckb.String(),
+					"sudt":    sudt.String(),
+				})
+			},
+		},
+		"status": {
+			syncChannels: true,
+			flags:        flagSet("status", asFlag),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				return out.Encode(channelInfos(c))
+			},
+		},
+		"history": {
+			flags: flagSet("history", asFlag, channelFlag),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				// Closed channels can only be selected by their ID.
+				var id channel.ID
+				if chID == "" {
+					ch, err := selectChannel(c, chID)
+					if err != nil {
+						return err
+					}
+					id = ch.ID()
+				} else {
+					var err error
+					if id, err = parseChannelID(chID); err != nil {
+						return err
+					}
+				}
+				states, err := c.ChannelHistory(id)
+				if err != nil {
+					return err
+				}
+				infos := []stateInfo{}
+				for _, state := range states {
+					infos = append(infos, newStateInfo(state))
+				}
+				return out.Encode(infos)
+			},
+		},
+		"serve": {
+			flags: flagSet("serve", asFlag, func(fs *flag.FlagSet) {
+				fs.BoolVar(&prompt, "prompt", false, "ask on stderr before accepting channel proposals")
+			}),
+			run: func(c *client.WalletClient, out *json.Encoder) error {
+				if prompt {
+					ws.SetApprover(approval.Prompt(os.Stdin, os.Stderr, c.Network, c.Decimals()), approvalTimeout(cfg))
+				}
+				c.Register(newJSONObserver(c, out))
+				sigs := make(chan os.Signal, 1)
+				signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+				<-sigs
+				return nil
+			},
+		},
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+
+	i, err := participantIndex(cfg, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	sgn, err := participantSigner(cfg, i)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	hosts := newWalletHosts(cfg)
+	var c *client.WalletClient
+	c, ws, err = newDemoClient(cfg, i, sgn, assetRegister, hosts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	defer func() {
+		hosts.shutdown()
+	}()
+	if err := waitReady(cfg, c); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if cmd.syncChannels {
+		if err := c.SyncChannels(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitCode(err)
+		}
+	}
+
+	if err := cmd.run(c, json.NewEncoder(os.Stdout)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// errUsage is wrapped by errors caused by invalid command line arguments.
+var errUsage = errors.New("invalid usage")
+
+// exitCode returns the exit code for the given error.
+func exitCode(err error) int {
+	var (
+		rejected *client.RejectedError
+		invalid  *client.InvalidInputError
+	)
+	switch {
+	case errors.As(err, &rejected):
+		return exitRejected
+	case errors.As(err, &invalid), errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		return exitFailure
+	}
+}
+
+// flagSet creates a flag set for the given command with the given flags.
+func flagSet(name string, flags ...func(fs *flag.FlagSet)) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, f := range flags {
+		f(fs)
+	}
+	return fs
+}
+
+// participantIndex returns the index of the configured participant with the
+// given name. Names are case-insensitive.
+func participantIndex(cfg *config.Config, name string) (int, error) {
+	for i, cp := range cfg.Participants {
+		if strings.EqualFold(cp.Name, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown participant %q", errUsage, name)
+}
+
+// participantAddress returns the wallet address of the configured participant
+// with the given name.
+func participantAddress(cfg *config.Config, name string) (*address.Participant, error) {
+	i, err := participantIndex(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	cp := cfg.Participants[i]
+	pub, err := keystore.ReadPublicKey(cp.KeyFile, cp.KeyFormat)
+	if err != nil {
+		return nil, fmt.Errorf("getting %s's public key: %w", cp.Name, err)
+	}
+	return address.NewDefaultParticipant(pub)
+}
+
+// selectChannel returns the channel with the given hex encoded ID. If id is
+// empty, the only open channel is returned.
+func selectChannel(c *client.WalletClient, id string) (*client.PaymentChannel, error) {
+	if id == "" {
+		chs := c.Channels()
+		if len(chs) != 1 {
+			return nil, fmt.Errorf("%w: %d open channels, select one with -channel", errUsage, len(chs))
+		}
+		return chs[0], nil
+	}
+	cid, err := parseChannelID(id)
+	if err != nil {
+		return nil, err
+	}
+	ch := c.Channel(cid)
+	if ch == nil {
+		return nil, fmt.Errorf("%w: no open channel with ID %s", errUsage, id)
+	}
+	return ch, nil
+}
+
+// parseChannelID parses a hex encoded channel ID.
+func parseChannelID(id string) (channel.ID, error) {
+	var cid channel.ID
+	b, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+	if err != nil || len(b) != len(cid) {
+		return cid, fmt.Errorf("%w: invalid channel ID %q", errUsage, id)
+	}
+	copy(cid[:], b)
+	return cid, nil
+}
+
+// channelInfo is the JSON representation of a channel.
+type channelInfo struct {
+	stateInfo
+	Idx int `json:"idx"`
+}
+
+// stateInfo is the JSON representation of a channel state.
+type stateInfo struct {
+	ID       string     `json:"id"`
+	Version  uint64     `json:"version"`
+	IsFinal  bool       `json:"is_final"`
+	Balances [][]string `json:"balances"`
+}
+
+func newChannelInfo(ch *client.PaymentChannel) channelInfo {
+	return channelInfo{stateInfo: newStateInfo(ch.State()), Idx: int(ch.Idx())}
+}
+
+func newStateInfo(state *channel.State) stateInfo {
+	bals := make([][]string, len(state.Allocation.Balances))
+	for i, assetBals := range state.Allocation.Balances {
+		bals[i] = make([]string, len(assetBals))
+		for j, bal := range assetBals {
+			bals[i][j] = bal.String()
+		}
+	}
+	return stateInfo{
+		ID:       hex.EncodeToString(state.ID[:]),
+		Version:  state.Version,
+		IsFinal:  state.IsFinal,
+		Balances: bals,
+	}
+}
+
+func channelInfos(c *client.WalletClient) []channelInfo {
+	infos := []channelInfo{}
+	for _, ch := range c.Channels() {
+		infos = append(infos, newChannelInfo(ch))
+	}
+	return infos
+}
+
+// jsonObserver prints the open channels on every state update and the
+// balance on every balance update as JSON lines.
+type jsonObserver struct {
+	id  uuid.UUID
+	c   *client.WalletClient
+	out *json.Encoder
+}
+
+func newJSONObserver(c *client.WalletClient, out *json.Encoder) *jsonObserver {
+	return &jsonObserver{id: uuid.New(), c: c, out: out}
+}
+
+func (o *jsonObserver) UpdateState(string) {
+	_ = o.out.Encode(map[string][]channelInfo{"channels": channelInfos(o.c)})
+}
+
+func (o *jsonObserver) UpdateBalance(string) {
+	_ = o.out.Encode(map[string]string{
+		"ckb":  o.c.GetBalance().String(),
+		"sudt": o.c.GetSudtBalance().String(),
+	})
+}
+
+func (o *jsonObserver) GetID() uuid.UUID {
+	return o.id
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-nervos-demo/client"
+	"perun.network/perun-nervos-demo/config"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"rejected", &client.RejectedError{Op: "open channel", Reason: "no"}, exitRejected},
+		{"wrapped rejected", fmt.Errorf("open: %w", &client.RejectedError{}), exitRejected},
+		{"invalid input", &client.InvalidInputError{Op: "pay", Msg: "too much"}, exitUsage},
+		{"usage", fmt.Errorf("%w: unknown asset", errUsage), exitUsage},
+		{"transport", &client.TransportError{Op: "pay", Err: errors.New("unavailable")}, exitFailure},
+		{"service", &client.ServiceError{Op: "pay", Err: errors.New("internal")}, exitFailure},
+		{"other", errors.New("failure"), exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.code, exitCode(tt.err))
+		})
+	}
+}
+
+func TestParseChannelID(t *testing.T) {
+	var want channel.ID
+	for i := range want {
+		want[i] = byte(i)
+	}
+	hexID := fmt.Sprintf("%x", want[:])
+
+	id, err := parseChannelID(hexID)
+	require.NoError(t, err)
+	require.Equal(t, want, id)
+	id, err = parseChannelID("0x" + hexID)
+	require.NoError(t, err)
+	require.Equal(t, want, id)
+
+	for _, invalid := range []string{"", "0x", "zz", hexID[2:], hexID + "00"} {
+		_, err := parseChannelID(invalid)
+		require.ErrorIs(t, err, errUsage, invalid)
+	}
+}
+
+func TestParticipantIndex(t *testing.T) {
+	cfg := &config.Config{Participants: []config.Participant{{Name: "alice"}, {Name: "Bob"}}}
+
+	i, err := participantIndex(cfg, "bob")
+	require.NoError(t, err)
+	require.Equal(t, 1, i)
+	i, err = participantIndex(cfg, "ALICE")
+	require.NoError(t, err)
+	require.Equal(t, 0, i)
+
+	_, err = participantIndex(cfg, "carol")
+	require.ErrorIs(t, err, errUsage)
+}
+
+func TestRunCommandUsage(t *testing.T) {
+	cfg := &config.Config{Participants: []config.Participant{{Name: "alice"}, {Name: "bob"}}}
+
+	// All of these fail before any service is contacted.
+	require.Equal(t, exitUsage, runCommand(cfg, []string{"unknown"}))
+	require.Equal(t, exitUsage, runCommand(cfg, []string{"status", "-unknown-flag"}))
+	require.Equal(t, exitUsage, runCommand(cfg, []string{"status", "-as", "carol"}))
+}
+
+func TestStateInfo(t *testing.T) {
+	state := &channel.State{
+		ID:      channel.ID{0xab},
+		Version: 3,
+		IsFinal: true,
+		Allocation: channel.Allocation{
+			Balances: channel.Balances{
+				{big.NewInt(100), big.NewInt(200)},
+				{big.NewInt(0), big.NewInt(7)},
+			},
+		},
+	}
+
+	b, err := json.Marshal(newStateInfo(state))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"id": "ab00000000000000000000000000000000000000000000000000000000000000",
+		"version": 3,
+		"is_final": true,
+		"balances": [["100", "200"], ["0", "7"]]
+	}`, string(b))
+}
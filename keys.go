@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/keystore"
+)
+
+const keyUsage = `Usage: perun-nervos-demo key <command> [flags]
+
+Commands:
+  create   generate a new key and store it in an encrypted keystore
+  import   encrypt a key given in another format: a plain key file exported
+           by ckb-cli (-format plain, the default), a JSON keystore of
+           ckb-cli (-format ckb-cli) or a file containing a BIP39 mnemonic
+           (-format mnemonic), whose key is derived at m/44'/309'/0'/0/index
+  export   decrypt a keystore into a plain key file
+  passwd   change the passphrase of a keystore
+
+The passphrase of a keystore, or the BIP39 passphrase of a mnemonic, is read
+from PERUN_DEMO_PASSPHRASE or the file given by -passphrase-file, and a new
+passphrase from PERUN_DEMO_NEW_PASSPHRASE or -new-passphrase-file. Otherwise,
+they are asked on the terminal. The address of the key is printed as JSON to
+stdout.
+`
+
+// Environment variables holding the passphrases of the key command.
+const (
+	passphraseEnv    = "PERUN_DEMO_PASSPHRASE"
+	newPassphraseEnv = "PERUN_DEMO_NEW_PASSPHRASE"
+)
+
+// runKeyCommand runs the key command given by args and returns the exit code
+// of the process.
+func runKeyCommand(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, keyUsage)
+		return exitUsage
+	}
+	var (
+		in, out        string
+		passphraseFile string
+		newPassFile    string
+		format         string
+		index          uint
+	)
+	fs := flag.NewFlagSet("key "+args[0], flag.ContinueOnError)
+	inFlag := func(usage string) { fs.StringVar(&in, "in", "", usage) }
+	outFlag := func(usage string) { fs.StringVar(&out, "out", "", usage) }
+	passFlag := func() {
+		fs.StringVar(&passphraseFile, "passphrase-file", "", "file containing the passphrase of the keystore")
+	}
+	newPassFlag := func() {
+		fs.StringVar(&newPassFile, "new-passphrase-file", "", "file containing the new passphrase")
+	}
+	current := func() keystore.PassphraseSource {
+		return keystore.PassphraseSource{Env: passphraseEnv, File: passphraseFile, Prompt: "Passphrase: "}
+	}
+	next := func() keystore.PassphraseSource {
+		return keystore.PassphraseSource{Env: newPassphraseEnv, File: newPassFile, Prompt: "New passphrase: "}
+	}
+
+	var run func() (*secp256k1.PublicKey, error)
+	switch args[0] {
+	case "create":
+		outFlag("path of the new keystore")
+		newPassFlag()
+		run = func() (*secp256k1.PublicKey, error) {
+			key, err := secp256k1.GeneratePrivateKey()
+			if err != nil {
+				return nil, err
+			}
+			return key.PubKey(), saveKeystore(out, key, next())
+		}
+	case "import":
+		inFlag("path of the key file")
+		outFlag("path of the new keystore")
+		fs.StringVar(&format, "format", keystore.FormatPlain, "format of the key file: plain, ckb-cli or mnemonic")
+		fs.UintVar(&index, "index", 0, "index of the derived key of a mnemonic")
+		passFlag()
+		newPassFlag()
+		run = func() (*secp256k1.PublicKey, error) {
+			key, err := importKey(in, format, uint32(index), current())
+			if err != nil {
+				return nil, err
+			}
+			return key.PubKey(), saveKeystore(out, key, next())
+		}
+	case "export":
+		inFlag("path of the keystore")
+		outFlag("path of the new plain key file")
+		passFlag()
+		run = func() (*secp256k1.PublicKey, error) {
+			key, err := keystore.Unlock(in, keystore.FormatKeystore, current())
+			if err != nil {
+				return nil, err
+			}
+			return key.PubKey(), keystore.SavePlain(out, key)
+		}
+	case "passwd":
+		inFlag("path of the keystore")
+		passFlag()
+		newPassFlag()
+		run = func() (*secp256k1.PublicKey, error) {
+			old, err := current().Passphrase()
+			if err != nil {
+				return nil, err
+			}
+			passphrase, err := next().NewPassphrase()
+			if err != nil {
+				return nil, err
+			}
+			if err := keystore.ChangePassphrase(in, old, passphrase); err != nil {
+				return nil, err
+			}
+			return keystore.ReadPublicKey(in, keystore.FormatKeystore)
+		}
+	default:
+		fmt.Fprint(os.Stderr, keyUsage)
+		return exitUsage
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	for _, name := range []string{"in", "out"} {
+		if f := fs.Lookup(name); f != nil && f.Value.String() == "" {
+			fmt.Fprintf(os.Stderr, "%v: missing -%s\n", errUsage, name)
+			return exitUsage
+		}
+	}
+
+	pub, err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	network, err := cfg.CKBNetwork()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	part, err := address.NewDefaultParticipant(pub)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	addr, err := part.ToCKBAddress(network).Encode()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(map[string]string{"address": addr})
+	return exitOK
+}
+
+// importKey reads the key file at path in the given format. The key of a
+// mnemonic is derived with the given index.
+func importKey(path, format string, index uint32, source keystore.PassphraseSource) (*secp256k1.PrivateKey, error) {
+	if format != keystore.FormatMnemonic {
+		return keystore.Unlock(path, format, source)
+	}
+	mnemonic, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := source.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return keystore.FromMnemonic(string(mnemonic), passphrase, index)
+}
+
+// saveKeystore encrypts the key with a new passphrase from source and writes
+// it to a new keystore at path.
+func saveKeystore(path string, key *secp256k1.PrivateKey, source keystore.PassphraseSource) error {
+	passphrase, err := source.NewPassphrase()
+	if err != nil {
+		return err
+	}
+	return keystore.Save(path, key, passphrase)
+}
@@ -0,0 +1,91 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+// ckbCLIFile is the JSON keystore written by ckb-cli. It follows version 3 of
+// the Ethereum keystore format, but the ciphertext is the master private key
+// of the account followed by its chain code.
+type ckbCLIFile struct {
+	Crypto struct {
+		Cipher       string `json:"cipher"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		Ciphertext string `json:"ciphertext"`
+		KDF        string `json:"kdf"`
+		KDFParams  struct {
+			DKLen int    `json:"dklen"`
+			N     int    `json:"n"`
+			R     int    `json:"r"`
+			P     int    `json:"p"`
+			Salt  string `json:"salt"`
+		} `json:"kdfparams"`
+		MAC string `json:"mac"`
+	} `json:"crypto"`
+}
+
+// DecryptCKBCLI decrypts a JSON keystore of ckb-cli with its passphrase and
+// returns the private key of the account, which is the key whose lock arg
+// ckb-cli shows for the account.
+func DecryptCKBCLI(data, passphrase []byte) (*secp256k1.PrivateKey, error) {
+	var f ckbCLIFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing ckb-cli keystore: %w", err)
+	}
+	c := f.Crypto
+	if c.KDF != "scrypt" || c.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported algorithms %s and %s", c.KDF, c.Cipher)
+	}
+	if c.KDFParams.DKLen < 32 {
+		return nil, fmt.Errorf("derived key length %d is too short", c.KDFParams.DKLen)
+	}
+	salt, err := hex.DecodeString(c.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	iv, err := hex.DecodeString(c.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid iv")
+	}
+	ciphertext, err := hex.DecodeString(c.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	mac, err := hex.DecodeString(c.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mac: %w", err)
+	}
+
+	derived, err := scrypt.Key(passphrase, salt, c.KDFParams.N, c.KDFParams.R, c.KDFParams.P, c.KDFParams.DKLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(derived[16:32])
+	hash.Write(ciphertext)
+	if subtle.ConstantTimeCompare(hash.Sum(nil), mac) != 1 {
+		return nil, ErrWrongPassphrase
+	}
+	block, err := aes.NewCipher(derived[:16])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(plain, ciphertext)
+	if len(plain) != 32 && len(plain) != 64 {
+		return nil, fmt.Errorf("unexpected key length %d", len(plain))
+	}
+	return secp256k1.PrivKeyFromBytes(plain[:32]), nil
+}
@@ -0,0 +1,285 @@
+// Package keystore stores private keys encrypted with a passphrase. The key
+// which encrypts a private key is derived from the passphrase with scrypt and
+// the private key is sealed with AES-256-GCM.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/scrypt"
+	"perun.network/perun-nervos-demo/deployment"
+)
+
+const (
+	// FormatKeystore is the format of key files written by this package.
+	FormatKeystore = "keystore"
+	// FormatPlain is the unencrypted format of ckb-cli's exported keys. It is
+	// only meant for development.
+	FormatPlain = "plain"
+	// FormatCKBCLI is the JSON keystore format of ckb-cli.
+	FormatCKBCLI = "ckb-cli"
+	// FormatMnemonic is a file containing a BIP39 mnemonic. The passphrase is
+	// the BIP39 passphrase and the key is derived at m/44'/309'/0'/0/0.
+	FormatMnemonic = "mnemonic"
+
+	version   = 1
+	kdfName   = "scrypt"
+	cipherAES = "aes-256-gcm"
+	keyLen    = 32
+	saltLen   = 32
+)
+
+// ErrWrongPassphrase is returned if a keystore cannot be decrypted.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+
+// KDFParams are the scrypt parameters with which the encryption key is
+// derived from the passphrase.
+type KDFParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+// DefaultKDFParams are the parameters of new keystores. They make guessing
+// passphrases expensive, as deriving a key takes about a second and 256 MiB
+// of memory.
+var DefaultKDFParams = KDFParams{N: 1 << 18, R: 8, P: 1}
+
+// file is the JSON encoding of a keystore. The public key is stored in plain
+// so that the address of a key is known without the passphrase.
+type file struct {
+	Version   int    `json:"version"`
+	PublicKey string `json:"public_key"`
+	KDF       struct {
+		Name string `json:"name"`
+		KDFParams
+		Salt string `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce string `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Encrypt encrypts the private key with the passphrase and returns the JSON
+// encoded keystore.
+func Encrypt(key *secp256k1.PrivateKey, passphrase []byte, params KDFParams) ([]byte, error) {
+	var f file
+	f.Version = version
+	pub := key.PubKey().SerializeCompressed()
+	f.PublicKey = hex.EncodeToString(pub)
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	f.KDF.Name = kdfName
+	f.KDF.KDFParams = params
+	f.KDF.Salt = hex.EncodeToString(salt)
+	aead, err := newAEAD(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	f.Cipher.Name = cipherAES
+	f.Cipher.Nonce = hex.EncodeToString(nonce)
+	f.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, key.Serialize(), pub))
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// Decrypt decrypts the JSON encoded keystore with the passphrase.
+func Decrypt(data, passphrase []byte) (*secp256k1.PrivateKey, error) {
+	f, pub, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(f.KDF.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	nonce, err := hex.DecodeString(f.Cipher.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	aead, err := newAEAD(passphrase, salt, f.KDF.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+	plain, err := aead.Open(nil, nonce, ciphertext, pub.SerializeCompressed())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	key := secp256k1.PrivKeyFromBytes(plain)
+	if !key.PubKey().IsEqual(pub) {
+		return nil, ErrWrongPassphrase
+	}
+	return key, nil
+}
+
+// parse decodes a keystore and checks that it uses the supported algorithms.
+func parse(data []byte) (*file, *secp256k1.PublicKey, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("parsing keystore: %w", err)
+	}
+	if f.Version != version {
+		return nil, nil, fmt.Errorf("unsupported keystore version %d", f.Version)
+	}
+	if f.KDF.Name != kdfName || f.Cipher.Name != cipherAES {
+		return nil, nil, fmt.Errorf("unsupported algorithms %s and %s", f.KDF.Name, f.Cipher.Name)
+	}
+	rawPub, err := hex.DecodeString(f.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	pub, err := secp256k1.ParsePubKey(rawPub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return &f, pub, nil
+}
+
+// newAEAD derives the encryption key from the passphrase.
+func newAEAD(passphrase, salt []byte, params KDFParams) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Save encrypts the key with the passphrase and writes it to a new file at
+// path, which must not exist yet.
+func Save(path string, key *secp256k1.PrivateKey, passphrase []byte) error {
+	data, err := Encrypt(key, passphrase, DefaultKDFParams)
+	if err != nil {
+		return err
+	}
+	return writeNew(path, data)
+}
+
+// SavePlain writes the unencrypted key to a new file at path in the format of
+// FormatPlain.
+func SavePlain(path string, key *secp256k1.PrivateKey) error {
+	return writeNew(path, []byte(hex.EncodeToString(key.Serialize())+"\n"))
+}
+
+// ChangePassphrase re-encrypts the keystore at path with a new passphrase.
+// The file is replaced atomically.
+func ChangePassphrase(path string, oldPassphrase, newPassphrase []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	key, err := Decrypt(data, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	if data, err = Encrypt(key, newPassphrase, DefaultKDFParams); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := writeNew(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeNew writes data to a new file which is only readable by the owner.
+func writeNew(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Unlock reads the key file at path in the given format, which is
+// FormatKeystore if empty. Keystores are decrypted with the passphrase
+// returned by source, which is not asked for plain keys.
+func Unlock(path, format string, source PassphraseSource) (*secp256k1.PrivateKey, error) {
+	switch format {
+	case FormatKeystore, "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		passphrase, err := source.Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		return Decrypt(data, passphrase)
+	case FormatPlain:
+		return deployment.GetKey(path)
+	case FormatCKBCLI, FormatMnemonic:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		passphrase, err := source.Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		if format == FormatCKBCLI {
+			return DecryptCKBCLI(data, passphrase)
+		}
+		return FromMnemonic(string(data), passphrase, 0)
+	default:
+		return nil, fmt.Errorf("unknown key format %q", format)
+	}
+}
+
+// ReadPublicKey returns the public key of the key file at path in the given
+// format without asking for a passphrase.
+func ReadPublicKey(path, format string) (*secp256k1.PublicKey, error) {
+	switch format {
+	case FormatKeystore, "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		_, pub, err := parse(data)
+		return pub, err
+	case FormatPlain:
+		key, err := deployment.GetKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return key.PubKey(), nil
+	case FormatCKBCLI, FormatMnemonic:
+		return nil, fmt.Errorf("the public key of a %s key needs its passphrase, import the key into a keystore first", format)
+	default:
+		return nil, fmt.Errorf("unknown key format %q", format)
+	}
+}
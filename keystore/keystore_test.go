@@ -0,0 +1,111 @@
+package keystore_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+	"perun.network/perun-nervos-demo/keystore"
+)
+
+// testParams keep the tests fast.
+var testParams = keystore.KDFParams{N: 1 << 10, R: 8, P: 1}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	data, err := keystore.Encrypt(key, []byte("secret"), testParams)
+	require.NoError(t, err)
+
+	decrypted, err := keystore.Decrypt(data, []byte("secret"))
+	require.NoError(t, err)
+	require.Equal(t, key.Serialize(), decrypted.Serialize())
+
+	_, err = keystore.Decrypt(data, []byte("wrong"))
+	require.ErrorIs(t, err, keystore.ErrWrongPassphrase)
+
+	// The public key is authenticated.
+	var f map[string]any
+	require.NoError(t, json.Unmarshal(data, &f))
+	other, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	f["public_key"] = hex.EncodeToString(other.PubKey().SerializeCompressed())
+	tampered, err := json.Marshal(f)
+	require.NoError(t, err)
+	_, err = keystore.Decrypt(tampered, []byte("secret"))
+	require.ErrorIs(t, err, keystore.ErrWrongPassphrase)
+}
+
+func TestUnlock(t *testing.T) {
+	dir := t.TempDir()
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	data, err := keystore.Encrypt(key, []byte("secret"), testParams)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "key.json")
+	require.NoError(t, os.WriteFile(path, data, 0600))
+
+	// The public key is readable without the passphrase.
+	pub, err := keystore.ReadPublicKey(path, keystore.FormatKeystore)
+	require.NoError(t, err)
+	require.True(t, pub.IsEqual(key.PubKey()))
+
+	passFile := filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(passFile, []byte("secret\n"), 0600))
+	unlocked, err := keystore.Unlock(path, "", keystore.PassphraseSource{File: passFile})
+	require.NoError(t, err)
+	require.Equal(t, key.Serialize(), unlocked.Serialize())
+
+	// The environment takes precedence over the file.
+	t.Setenv("TEST_PASSPHRASE", "wrong")
+	_, err = keystore.Unlock(path, "", keystore.PassphraseSource{Env: "TEST_PASSPHRASE", File: passFile})
+	require.ErrorIs(t, err, keystore.ErrWrongPassphrase)
+
+	// Plain keys are read without a passphrase.
+	plainPath := filepath.Join(dir, "key.pk")
+	require.NoError(t, keystore.SavePlain(plainPath, key))
+	plain, err := keystore.Unlock(plainPath, keystore.FormatPlain, keystore.PassphraseSource{})
+	require.NoError(t, err)
+	require.Equal(t, key.Serialize(), plain.Serialize())
+	require.Error(t, keystore.SavePlain(plainPath, key), "existing files are not overwritten")
+}
+
+func TestDecryptCKBCLI(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	// ckb-cli encrypts the master key followed by its chain code.
+	plain := append(key.Serialize(), make([]byte, 32)...)
+	salt, iv := make([]byte, 32), make([]byte, aes.BlockSize)
+	derived, err := scrypt.Key([]byte("secret"), salt, 1<<10, 8, 1, 32)
+	require.NoError(t, err)
+	block, err := aes.NewCipher(derived[:16])
+	require.NoError(t, err)
+	ciphertext := make([]byte, len(plain))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, plain)
+	mac := sha3.NewLegacyKeccak256()
+	mac.Write(derived[16:32])
+	mac.Write(ciphertext)
+	data := fmt.Sprintf(`{"crypto": {
+		"cipher": "aes-128-ctr",
+		"cipherparams": {"iv": "%x"},
+		"ciphertext": "%x",
+		"kdf": "scrypt",
+		"kdfparams": {"dklen": 32, "n": 1024, "p": 1, "r": 8, "salt": "%x"},
+		"mac": "%x"
+	}, "id": "2c6a5fbb-b5a5-4e6b-8a32-7c6b5a9c1a4e", "version": 3}`, iv, ciphertext, salt, mac.Sum(nil))
+
+	decrypted, err := keystore.DecryptCKBCLI([]byte(data), []byte("secret"))
+	require.NoError(t, err)
+	require.Equal(t, key.Serialize(), decrypted.Serialize())
+	_, err = keystore.DecryptCKBCLI([]byte(data), []byte("wrong"))
+	require.ErrorIs(t, err, keystore.ErrWrongPassphrase)
+}
@@ -0,0 +1,134 @@
+package keystore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// CKBCoinType is the coin type of CKB in BIP44 derivation paths. Keys of
+// mnemonics are derived at m/44'/309'/0'/0/index.
+const CKBCoinType = 309
+
+// hardened is the offset of hardened BIP32 child indices.
+const hardened = 1 << 31
+
+//go:embed bip39_english.txt
+var bip39English string
+
+// bip39Words maps the words of the English BIP39 word list to their index.
+var bip39Words = func() map[string]int {
+	words := strings.Fields(bip39English)
+	m := make(map[string]int, len(words))
+	for i, w := range words {
+		m[w] = i
+	}
+	return m
+}()
+
+// FromMnemonic derives the key of the receiving address with the given index
+// from a BIP39 mnemonic of the English word list and its passphrase, which
+// may be empty. The key is derived at m/44'/309'/0'/0/index.
+func FromMnemonic(mnemonic string, passphrase []byte, index uint32) (*secp256k1.PrivateKey, error) {
+	seed, err := mnemonicSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return deriveKey(seed, []uint32{44 + hardened, CKBCoinType + hardened, hardened, 0, index})
+}
+
+// mnemonicSeed returns the BIP39 seed of a mnemonic and its passphrase.
+func mnemonicSeed(mnemonic string, passphrase []byte) ([]byte, error) {
+	words := strings.Fields(norm.NFKD.String(mnemonic))
+	if err := checkMnemonic(words); err != nil {
+		return nil, err
+	}
+	salt := append([]byte("mnemonic"), norm.NFKD.Bytes(passphrase)...)
+	return pbkdf2.Key([]byte(strings.Join(words, " ")), salt, 2048, 64, sha512.New), nil
+}
+
+// checkMnemonic checks that the words are a valid mnemonic, including its
+// checksum.
+func checkMnemonic(words []string) error {
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return fmt.Errorf("mnemonic has %d words instead of 12, 15, 18, 21 or 24", len(words))
+	}
+	// Every word encodes 11 bits of the entropy followed by its checksum.
+	bits := make([]byte, 0, len(words)*11)
+	for _, w := range words {
+		idx, ok := bip39Words[w]
+		if !ok {
+			return fmt.Errorf("unknown mnemonic word %q", w)
+		}
+		for i := 10; i >= 0; i-- {
+			bits = append(bits, byte(idx>>i)&1)
+		}
+	}
+	checksumLen := len(bits) / 33
+	entropy := make([]byte, (len(bits)-checksumLen)/8)
+	for i, b := range bits[:len(entropy)*8] {
+		entropy[i/8] |= b << (7 - i%8)
+	}
+	hash := sha256.Sum256(entropy)
+	for i, b := range bits[len(entropy)*8:] {
+		if hash[i/8]>>(7-i%8)&1 != b {
+			return errors.New("invalid mnemonic checksum")
+		}
+	}
+	return nil
+}
+
+// deriveKey derives the private key at the given BIP32 path from a seed.
+func deriveKey(seed []byte, path []uint32) (*secp256k1.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	var key secp256k1.ModNScalar
+	if overflow := key.SetByteSlice(sum[:32]); overflow || key.IsZero() {
+		return nil, errors.New("invalid master key")
+	}
+	chainCode := sum[32:]
+	for _, index := range path {
+		var err error
+		if chainCode, err = deriveChild(&key, chainCode, index); err != nil {
+			return nil, err
+		}
+	}
+	return secp256k1.NewPrivateKey(&key), nil
+}
+
+// deriveChild replaces key by its child with the given index and returns the
+// chain code of the child.
+func deriveChild(key *secp256k1.ModNScalar, chainCode []byte, index uint32) ([]byte, error) {
+	var data []byte
+	if index >= hardened {
+		k := key.Bytes()
+		data = append([]byte{0}, k[:]...)
+	} else {
+		data = secp256k1.NewPrivateKey(key).PubKey().SerializeCompressed()
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	var tweak secp256k1.ModNScalar
+	if overflow := tweak.SetByteSlice(sum[:32]); overflow {
+		return nil, fmt.Errorf("invalid child key %d", index)
+	}
+	key.Add(&tweak)
+	if key.IsZero() {
+		return nil, fmt.Errorf("invalid child key %d", index)
+	}
+	return sum[32:], nil
+}
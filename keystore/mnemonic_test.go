@@ -0,0 +1,40 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMnemonicSeed(t *testing.T) {
+	mnemonic := strings.Repeat("abandon ", 11) + "about"
+	seed, err := mnemonicSeed(mnemonic, []byte("TREZOR"))
+	require.NoError(t, err)
+	require.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04", hex.EncodeToString(seed))
+
+	_, err = mnemonicSeed(strings.Repeat("abandon ", 12), nil)
+	require.ErrorContains(t, err, "checksum")
+	_, err = mnemonicSeed(strings.Repeat("abandon ", 11)+"nervos", nil)
+	require.ErrorContains(t, err, "unknown mnemonic word")
+	_, err = mnemonicSeed("abandon about", nil)
+	require.ErrorContains(t, err, "2 words")
+}
+
+func TestDeriveKey(t *testing.T) {
+	// Test vector 1 of BIP32.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	for _, v := range []struct {
+		path []uint32
+		key  string
+	}{
+		{nil, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{[]uint32{hardened}, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{[]uint32{hardened, 1, 2 + hardened, 2, 1000000000}, "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	} {
+		key, err := deriveKey(seed, v.path)
+		require.NoError(t, err)
+		require.Equal(t, v.key, hex.EncodeToString(key.Serialize()), "path %v", v.path)
+	}
+}
@@ -0,0 +1,85 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// PassphraseSource tells where the passphrase of a keystore comes from. The
+// environment variable Env takes precedence over the file File. If neither is
+// set, the user is asked on the terminal with Prompt.
+type PassphraseSource struct {
+	Env    string
+	File   string
+	Prompt string
+}
+
+// Passphrase returns the passphrase from the first available source.
+func (s PassphraseSource) Passphrase() ([]byte, error) {
+	if s.Env != "" {
+		if p, ok := os.LookupEnv(s.Env); ok {
+			return []byte(p), nil
+		}
+	}
+	if s.File != "" {
+		data, err := os.ReadFile(s.File)
+		if err != nil {
+			return nil, fmt.Errorf("reading passphrase file: %w", err)
+		}
+		// Only the first line counts, so that files may end with a newline.
+		line, _, _ := bytes.Cut(data, []byte("\n"))
+		return bytes.TrimSuffix(line, []byte("\r")), nil
+	}
+	return prompt(s.Prompt)
+}
+
+// NewPassphrase returns a new passphrase, which the user has to enter twice
+// if it is asked on the terminal. Empty passphrases are refused.
+func (s PassphraseSource) NewPassphrase() ([]byte, error) {
+	p, err := s.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if !s.interactive() {
+		return p, nil
+	}
+	repeated, err := prompt("Repeat the passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, repeated) {
+		return nil, errors.New("passphrases do not match")
+	}
+	return p, nil
+}
+
+// interactive returns whether the passphrase is asked on the terminal.
+func (s PassphraseSource) interactive() bool {
+	if s.Env != "" {
+		if _, ok := os.LookupEnv(s.Env); ok {
+			return false
+		}
+	}
+	return s.File == ""
+}
+
+// prompt reads a passphrase from the terminal without echoing it.
+func prompt(msg string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no passphrase given and stdin is not a terminal")
+	}
+	if msg == "" {
+		msg = "Passphrase: "
+	}
+	fmt.Fprint(os.Stderr, msg)
+	defer fmt.Fprintln(os.Stderr)
+	return term.ReadPassword(fd)
+}
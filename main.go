@@ -1,31 +1,36 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"time"
 
-	"polycry.pt/poly-go/sync"
-
-	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/rpc"
 	"perun.network/go-perun/channel"
-	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/backend"
 	"perun.network/perun-ckb-backend/channel/asset"
-	"perun.network/perun-ckb-backend/wallet"
 	vc "perun.network/perun-demo-tui/client"
 	"perun.network/perun-demo-tui/view"
+	"perun.network/perun-nervos-demo/approval"
+	"perun.network/perun-nervos-demo/audit"
 	"perun.network/perun-nervos-demo/client"
+	"perun.network/perun-nervos-demo/config"
 	"perun.network/perun-nervos-demo/deployment"
+	"perun.network/perun-nervos-demo/keystore"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/signer"
+	"perun.network/perun-nervos-demo/wallet_service"
 )
 
 const (
-	rpcNodeURL = "http://localhost:8114"
-	network    = types.NetworkTest
-	aliceWSURL = "localhost:50051"
-	bobWSURL   = "localhost:50052"
-	aliceCSURL = "localhost:4321"
-	bobCSURL   = "localhost:4322"
+	// configPath is the default location of the demo configuration.
+	configPath = "config.yaml"
+	// signerTimeout bounds the time to connect to a signer daemon.
+	signerTimeout = 10 * time.Second
 )
 
 func SetLogFile(path string) {
@@ -82,81 +87,252 @@ func newAssetRegister(assets []channel.Asset, names []string) (*AssetRegister, e
 	return assetRegister, nil
 }
 
-func main() {
-	SetLogFile("demo.log")
+// loadSigners creates the signers of all configured participants.
+func loadSigners(cfg *config.Config) ([]signer.Signer, error) {
+	signers := make([]signer.Signer, len(cfg.Participants))
+	for i := range cfg.Participants {
+		sgn, err := participantSigner(cfg, i)
+		if err != nil {
+			return nil, err
+		}
+		signers[i] = sgn
+	}
+	return signers, nil
+}
 
-	ckbAsset := asset.NewCKBytesAsset()
+// participantSigner connects to the signer daemon of the i'th configured
+// participant or, if none is configured, unlocks the participant's key.
+func participantSigner(cfg *config.Config, i int) (signer.Signer, error) {
+	cp := cfg.Participants[i]
+	if cp.Signer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), signerTimeout)
+		defer cancel()
+		sgn, err := signer.Dial(ctx, cp.Signer)
+		if err != nil {
+			return nil, fmt.Errorf("connecting to %s's signer: %w", cp.Name, err)
+		}
+		return sgn, nil
+	}
+	network, err := cfg.CKBNetwork()
+	if err != nil {
+		return nil, err
+	}
+	key, err := participantKey(cfg, i)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewLocalSigner(key, network), nil
+}
 
-	assetRegister, err := newAssetRegister([]channel.Asset{ckbAsset}, []string{"CKBytes"})
+// participantKey unlocks the private key of the i'th configured participant.
+func participantKey(cfg *config.Config, i int) (*secp256k1.PrivateKey, error) {
+	cp := cfg.Participants[i]
+	source := keystore.PassphraseSource{
+		Env:    config.PassphraseEnv(cp.Name),
+		File:   cp.PassphraseFile,
+		Prompt: fmt.Sprintf("Passphrase of %s's key: ", cp.Name),
+	}
+	key, err := keystore.Unlock(cp.KeyFile, cp.KeyFormat, source)
 	if err != nil {
-		log.Fatalf("error creating mapping: %v", err)
+		return nil, fmt.Errorf("getting %s's private key: %w", cp.Name, err)
 	}
+	return key, nil
+}
 
-	keyAlice, err := deployment.GetKey("./devnet/accounts/alice.pk")
+// newDemoClient creates the wallet client of the i'th configured participant
+// and the wallet service of its account, which is hosted at the participant's
+// wallet service address.
+func newDemoClient(cfg *config.Config, i int, sgn signer.Signer, assetRegister *AssetRegister, hosts *walletHosts) (*client.WalletClient, *wallet_service.MyWalletService, error) {
+	network, err := cfg.CKBNetwork()
 	if err != nil {
-		log.Fatalf("error getting alice's private key: %v", err)
+		return nil, nil, err
 	}
-	keyBob, err := deployment.GetKey("./devnet/accounts/bob.pk")
+	d, err := loadDeployment(cfg)
 	if err != nil {
-		log.Fatalf("error getting bob's private key: %v", err)
+		return nil, nil, err
+	}
+	cp := cfg.Participants[i]
+	host, err := hosts.get(cp.WalletService)
+	if err != nil {
+		return nil, nil, err
+	}
+	csCreds, err := channelServiceCredentials(cfg, cp.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	wsCreds, err := walletServiceCredentials(cfg, cp.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, err := rpc.Dial(cfg.NodeURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds := wallet_service.ChallengeDurationBounds{Min: cfg.MinChallengeDuration, Max: cfg.MaxChallengeDuration}
+	ws, err := wallet_service.NewWalletService(cp.Name, sgn, network, cp.WalletDBDir, bounds, d, chain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating wallet service: %w", err)
+	}
+	if cfg.TLS != nil {
+		ws.SetAuthorizedCaller(mtls.ChannelServiceOf(cp.Name))
+		ws.SetAuthorizedClient(mtls.ClientOf(cp.Name))
+	}
+	if cp.ApprovalRules != "" {
+		rules, err := approval.LoadRules(cp.ApprovalRules)
+		if err != nil {
+			ws.Close()
+			return nil, nil, err
+		}
+		ws.SetApprover(rules.Approver(network, sudtDecimals(cfg, d)), approvalTimeout(cfg))
+	}
+	if cp.AuditLog != "" {
+		l, err := audit.Open(cp.AuditLog)
+		if err != nil {
+			ws.Close()
+			return nil, nil, err
+		}
+		ws.SetAuditLog(l)
+	}
+	if err := host.Add(ws); err != nil {
+		ws.Close()
+		return nil, nil, fmt.Errorf("hosting wallet service: %w", err)
 	}
-	aliceAccount := wallet.NewAccountFromPrivateKey(keyAlice)
-	bobAccount := wallet.NewAccountFromPrivateKey(keyBob)
-
-	parties := []gpwallet.Address{aliceAccount.Address(), bobAccount.Address()}
 
-	// Create a wait group
-	var wg sync.WaitGroup
-	// Setup clients
-	log.Println("Setting up clients.")
-	alice, err := client.NewWalletClient(
-		"Alice",
+	c, err := client.NewWalletClient(
+		cp.Name,
 		network,
-		rpcNodeURL,
-		parties,
-		[]channel.Asset{ckbAsset},
-		aliceWSURL,
-		aliceCSURL,
-		aliceAccount,
-		keyAlice,
+		cfg.NodeURL,
+		cp.WalletService,
+		wsCreds,
+		cp.ChannelService,
+		csCreds,
+		sgn,
 		assetRegister,
-		&wg,
+		sudtDecimals(cfg, d),
+		cfg.ChallengeDuration,
+		bounds,
 	)
 	if err != nil {
-		log.Fatalf("error creating alice's client: %v", err)
+		return nil, nil, err
 	}
-	bob, err := client.NewWalletClient(
-		"Bob",
-		network,
-		rpcNodeURL,
-		parties,
-		[]channel.Asset{ckbAsset},
-		bobWSURL,
-		bobCSURL,
-		bobAccount,
-		keyBob,
-		assetRegister,
-		&wg,
-	)
+	return c, ws, nil
+}
+
+// approvalTimeout returns the configured time in which incoming proposals
+// have to be approved.
+func approvalTimeout(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.ApprovalTimeout) * time.Second
+}
+
+// waitReady waits until the services of the clients are ready, at most for
+// the configured ready timeout.
+func waitReady(cfg *config.Config, clients ...*client.WalletClient) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ReadyWait())
+	defer cancel()
+	for _, c := range clients {
+		if err := c.WaitReady(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDeployment loads the deployment of the Perun scripts, against which the
+// wallet services check the transactions they sign.
+func loadDeployment(cfg *config.Config) (backend.Deployment, error) {
+	sudtOwnerLockArg, err := deployment.GetSUDTOwnerLockArg(cfg.SUDTOwnerLockArgFile())
+	if err != nil {
+		return backend.Deployment{}, fmt.Errorf("getting SUDT owner lock arg: %w", err)
+	}
+	d, _, err := deployment.GetDeployment(cfg.MigrationDir(), cfg.SystemScriptsDir(), sudtOwnerLockArg)
+	if err != nil {
+		return backend.Deployment{}, fmt.Errorf("getting deployment: %w", err)
+	}
+	return d, nil
+}
+
+// sudtDecimals returns the configured decimals of the SUDTs of the deployment
+// d.
+func sudtDecimals(cfg *config.Config, d backend.Deployment) client.Decimals {
+	decimals := make(client.Decimals, len(d.SUDTs))
+	for h := range d.SUDTs {
+		decimals[h] = cfg.SUDTDecimals
+	}
+	return decimals
+}
+
+// newDemoAssetRegister creates the register of all assets used in the demo.
+func newDemoAssetRegister() (*AssetRegister, error) {
+	return newAssetRegister([]channel.Asset{asset.NewCKBytesAsset()}, []string{"CKBytes"})
+}
+
+func main() {
+	SetLogFile("demo.log")
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+
+	// The key command manages keystores, the audit command verifies audit
+	// logs and the certs command generates the certificates for mutual TLS.
+	// Any other arguments select the headless command mode.
+	if len(os.Args) > 1 && os.Args[1] == "key" {
+		os.Exit(runKeyCommand(cfg, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAuditCommand(cfg, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "certs" {
+		os.Exit(runCertsCommand(cfg, os.Args[2:]))
+	}
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1:]))
+	}
+
+	assetRegister, err := newDemoAssetRegister()
+	if err != nil {
+		log.Fatalf("error creating mapping: %v", err)
+	}
+
+	signers, err := loadSigners(cfg)
 	if err != nil {
-		log.Fatalf("error creating bob's client: %v", err)
+		log.Fatalf("error loading signers: %v", err)
+	}
+
+	// Setup clients
+	log.Println("Setting up clients.")
+	hosts := newWalletHosts(cfg)
+	clients := make([]vc.DemoClient, len(cfg.Participants))
+	walletClients := make([]*client.WalletClient, len(cfg.Participants))
+	for i, cp := range cfg.Participants {
+		c, _, err := newDemoClient(cfg, i, signers[i], assetRegister, hosts)
+		if err != nil {
+			log.Fatalf("error creating %s's client: %v", cp.Name, err)
+		}
+		walletClients[i] = c
+		clients[i] = client.NewDemoClient(c)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.WalletAccounts != nil {
+		if err := hostWalletAccounts(ctx, cfg, hosts); err != nil {
+			log.Fatalf("error hosting wallet accounts: %v", err)
+		}
 	}
 	// Handle termination signal in a separate goroutine
 	defer func() {
 		log.Println("Main process received shutdown signal")
 
-		// Shutdown wallet services
-		alice.WalletServer.Shutdown(&wg)
-		bob.WalletServer.Shutdown(&wg)
-
-		// Wait for all wallet services to shut down
-		wg.Wait()
+		// Shutdown wallet services and wait until they are stopped
+		cancel()
+		hosts.shutdown()
 
 		log.Println("Main process exiting")
 		os.Exit(0)
 	}()
-	time.Sleep(2 * time.Second) // Wait for clients to connect with channel service
-	clients := []vc.DemoClient{alice, bob}
+	if err := waitReady(cfg, walletClients...); err != nil {
+		log.Fatalf("error waiting for services: %v", err)
+	}
 	_ = view.RunDemo("Perun Nervos Channel Service Demo", clients, assetRegister)
 
 }
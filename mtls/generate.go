@@ -0,0 +1,187 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// validity is the validity period of generated certificates.
+const validity = 10 * 365 * 24 * time.Hour
+
+// Generate writes certificates for the server and client identities to dir.
+// They are issued by the CA in dir, which is created if there is none yet.
+// Server certificates are valid for localhost as well. Existing certificates
+// are kept, and the paths of the written files are returned.
+func Generate(dir string, servers, clients []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	caFiles := DirFiles(dir, "ca")
+	caCert, caKey, written, err := loadOrCreateCA(caFiles.Cert, caFiles.Key)
+	if err != nil {
+		return nil, err
+	}
+	issue := func(identity string, server bool) error {
+		f := DirFiles(dir, identity)
+		if _, err := os.Stat(f.Cert); err == nil {
+			return nil
+		}
+		cert, key, err := issueCert(caCert, caKey, identity, server)
+		if err != nil {
+			return fmt.Errorf("issuing certificate of %s: %w", identity, err)
+		}
+		if err := writePEM(f.Key, "EC PRIVATE KEY", key, 0600); err != nil {
+			return err
+		}
+		if err := writePEM(f.Cert, "CERTIFICATE", cert, 0644); err != nil {
+			return err
+		}
+		written = append(written, f.Cert, f.Key)
+		return nil
+	}
+	for _, id := range servers {
+		if err := issue(id, true); err != nil {
+			return written, err
+		}
+	}
+	for _, id := range clients {
+		if err := issue(id, false); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// loadOrCreateCA loads the CA from its files or creates it, in which case the
+// written files are returned.
+func loadOrCreateCA(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, []string, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return createCA(certPath, keyPath)
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading CA key: %w", err)
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, nil, errors.New("invalid CA files")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing CA certificate: %w", err)
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing CA key: %w", err)
+	}
+	return cert, key, nil, nil
+}
+
+// createCA creates a new CA and writes it to its files.
+func createCA(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, []string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Perun Nervos Demo CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := writePEM(keyPath, "EC PRIVATE KEY", keyDER, 0600); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := writePEM(certPath, "CERTIFICATE", der, 0644); err != nil {
+		return nil, nil, nil, err
+	}
+	return cert, key, []string{certPath, keyPath}, nil
+}
+
+// issueCert issues a certificate for the identity and returns it together
+// with its private key, both DER encoded.
+func issueCert(ca *x509.Certificate, caKey *ecdsa.PrivateKey, identity string, server bool) (cert, key []byte, err error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: identity},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{identity, "localhost"}
+		tmpl.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	}
+	cert, err = x509.CreateCertificate(rand.Reader, tmpl, ca, &priv.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err = x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// newSerial returns a random certificate serial number.
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// writePEM writes the PEM block to a new file at path.
+func writePEM(path, typ string, der []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: typ, Bytes: der}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
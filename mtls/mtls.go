@@ -0,0 +1,158 @@
+// Package mtls sets up mutual TLS between the demo clients, the channel
+// service, the wallet services and the watchtower. All certificates are issued by one CA and
+// name their holder in the common name, which is its identity:
+//
+//	wallet-service          the wallet services
+//	channel-service         the channel service
+//	<name>.channel-service  the channel service acting for participant name
+//	<name>.client           the demo client of participant name
+//	watchtower              the watchtower
+//
+// The certificates are stored in one directory, see Files.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Identities of the services.
+const (
+	WalletService  = "wallet-service"
+	ChannelService = "channel-service"
+	Watchtower     = "watchtower"
+)
+
+// ChannelServiceOf returns the identity of the channel service acting for the
+// participant with the given name.
+func ChannelServiceOf(name string) string {
+	return strings.ToLower(name) + "." + ChannelService
+}
+
+// ClientOf returns the identity of the demo client of the participant with
+// the given name.
+func ClientOf(name string) string {
+	return strings.ToLower(name) + ".client"
+}
+
+// Files are the files of one identity in a certificate directory.
+type Files struct {
+	CA   string // Certificate of the CA.
+	Cert string // Certificate of the identity.
+	Key  string // Private key of the identity.
+}
+
+// DirFiles returns the files of the identity in the directory dir, which are
+// ca.pem, <identity>.pem and <identity>-key.pem.
+func DirFiles(dir, identity string) Files {
+	return Files{
+		CA:   filepath.Join(dir, "ca.pem"),
+		Cert: filepath.Join(dir, identity+".pem"),
+		Key:  filepath.Join(dir, identity+"-key.pem"),
+	}
+}
+
+// load reads the certificate and the CA pool of f.
+func (f Files) load() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(f.Cert, f.Key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("loading certificate: %w", err)
+	}
+	ca, err := os.ReadFile(f.CA)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("loading CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return tls.Certificate{}, nil, errors.New("invalid CA certificate")
+	}
+	return cert, pool, nil
+}
+
+// ServerCredentials returns the credentials of a server with the certificate
+// of f, which only accepts clients with a certificate of the CA.
+func ServerCredentials(f Files) (credentials.TransportCredentials, error) {
+	cert, pool, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
+
+// ClientCredentials returns the credentials of a client with the certificate
+// of f, which only accepts a server with the given identity.
+func ClientCredentials(f Files, server string) (credentials.TransportCredentials, error) {
+	cert, pool, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   server,
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
+
+// PeerIdentity returns the identity of the verified client certificate of
+// the caller of a request.
+func PeerIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+// Authorize returns an error if the caller of a request does not have the
+// given identity.
+func Authorize(ctx context.Context, identity string) error {
+	id, ok := PeerIdentity(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "no client certificate")
+	}
+	if id != identity {
+		return status.Errorf(codes.PermissionDenied, "%s is not authorized", id)
+	}
+	return nil
+}
+
+// RequireIdentity returns server options which only let callers with the
+// given identity through.
+func RequireIdentity(identity string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := Authorize(ctx, identity); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := Authorize(ss.Context(), identity); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
@@ -0,0 +1,51 @@
+package mtls_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"perun.network/perun-nervos-demo/mtls"
+)
+
+func TestRequireIdentity(t *testing.T) {
+	dir := t.TempDir()
+	clients := []string{mtls.ClientOf("Alice"), mtls.ClientOf("Bob")}
+	written, err := mtls.Generate(dir, []string{mtls.ChannelService}, clients)
+	require.NoError(t, err)
+	require.Len(t, written, 8, "CA and three certificates with their keys")
+	written, err = mtls.Generate(dir, []string{mtls.ChannelService}, clients)
+	require.NoError(t, err)
+	require.Empty(t, written, "existing certificates are kept")
+
+	creds, err := mtls.ServerCredentials(mtls.DirFiles(dir, mtls.ChannelService))
+	require.NoError(t, err)
+	s := grpc.NewServer(append(mtls.RequireIdentity(mtls.ClientOf("Alice")), grpc.Creds(creds))...)
+	grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	check := func(identity, server string) error {
+		creds, err := mtls.ClientCredentials(mtls.DirFiles(dir, identity), server)
+		require.NoError(t, err)
+		conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		return err
+	}
+	require.NoError(t, check(mtls.ClientOf("Alice"), mtls.ChannelService))
+	require.Equal(t, codes.PermissionDenied, status.Code(check(mtls.ClientOf("Bob"), mtls.ChannelService)))
+	require.Error(t, check(mtls.ClientOf("Alice"), mtls.WalletService), "the server identity is checked")
+}
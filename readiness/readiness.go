@@ -0,0 +1,140 @@
+// Package readiness reports whether the gRPC services of the demo are ready
+// through the standard gRPC health service, and lets their clients wait until
+// they are. A service is ready once all of its checks pass, e.g., the CKB node
+// is reachable and its indexer is in sync.
+package readiness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// DefaultInterval is the interval in which the checks of a service are
+	// run again.
+	DefaultInterval = 5 * time.Second
+	// MaxIndexerLag is the number of blocks by which the indexer may lag
+	// behind the node and still count as in sync.
+	MaxIndexerLag = 10
+	// pollInterval is the interval in which WaitReady asks for the status.
+	pollInterval = 500 * time.Millisecond
+)
+
+// Check returns an error if a precondition of the readiness is not met.
+type Check func(ctx context.Context) error
+
+// Chain is the part of the CKB RPC client which is checked.
+type Chain interface {
+	GetTipBlockNumber(ctx context.Context) (uint64, error)
+	GetIndexerTip(ctx context.Context) (*indexer.TipHeader, error)
+}
+
+// NodeCheck checks that the CKB node is reachable.
+func NodeCheck(chain Chain) Check {
+	return func(ctx context.Context) error {
+		if _, err := chain.GetTipBlockNumber(ctx); err != nil {
+			return fmt.Errorf("node unreachable: %w", err)
+		}
+		return nil
+	}
+}
+
+// IndexerCheck checks that the indexer of the CKB node is in sync with the
+// node.
+func IndexerCheck(chain Chain) Check {
+	return func(ctx context.Context) error {
+		tip, err := chain.GetTipBlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("node unreachable: %w", err)
+		}
+		indexed, err := chain.GetIndexerTip(ctx)
+		if err != nil {
+			return fmt.Errorf("indexer unreachable: %w", err)
+		}
+		if indexed == nil || indexed.BlockNumber+MaxIndexerLag < tip {
+			return fmt.Errorf("indexer is behind the node tip %d", tip)
+		}
+		return nil
+	}
+}
+
+// NewServer creates a health server at which the overall status of the
+// server s is NOT_SERVING until Monitor reports otherwise.
+func NewServer(s *grpc.Server) *health.Server {
+	srv := health.NewServer()
+	srv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s, srv)
+	return srv
+}
+
+// Monitor runs the checks in the given interval until ctx is done and sets
+// the overall status of srv to SERVING while all of them pass. The name of the
+// service is used in the logs.
+func Monitor(ctx context.Context, srv *health.Server, name string, interval time.Duration, checks ...Check) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last error = errors.New("not checked yet")
+	for {
+		err := runChecks(ctx, interval, checks)
+		switch {
+		case err == nil && last != nil:
+			log.Printf("%s is ready", name)
+			srv.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		case err != nil && (last == nil || err.Error() != last.Error()):
+			log.Printf("%s is not ready: %v", name, err)
+			srv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+		last = err
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runChecks runs the checks, each with the given timeout, and returns the
+// first error.
+func runChecks(ctx context.Context, timeout time.Duration, checks []Check) error {
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		err := check(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WaitReady waits until the server at cc reports that it is ready, or returns
+// an error if ctx is done before.
+func WaitReady(ctx context.Context, cc grpc.ClientConnInterface) error {
+	client := healthpb.NewHealthClient(cc)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var status error
+	for {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		if err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING {
+			return nil
+		} else if err == nil {
+			status = fmt.Errorf("status %v", resp.Status)
+		} else {
+			status = err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not ready: %w", status)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package readiness_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"perun.network/perun-nervos-demo/readiness"
+)
+
+func TestWaitReady(t *testing.T) {
+	s := grpc.NewServer()
+	health := readiness.NewServer(s)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var ready atomic.Bool
+	check := func(context.Context) error {
+		if !ready.Load() {
+			return errors.New("not yet")
+		}
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go readiness.Monitor(ctx, health, "test service", 10*time.Millisecond, check)
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	require.Error(t, readiness.WaitReady(waitCtx, conn), "not ready while the check fails")
+
+	ready.Store(true)
+	waitCtx, waitCancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer waitCancel()
+	require.NoError(t, readiness.WaitReady(waitCtx, conn))
+}
+
+func TestMonitor(t *testing.T) {
+	s := grpc.NewServer()
+	health := readiness.NewServer(s)
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		return resp.Status
+	}
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(), "not serving before the first check")
+
+	var failing atomic.Bool
+	var later atomic.Int32
+	checks := []readiness.Check{
+		func(context.Context) error {
+			if failing.Load() {
+				return errors.New("node unreachable")
+			}
+			return nil
+		},
+		func(context.Context) error {
+			later.Add(1)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		readiness.Monitor(ctx, health, "test service", 10*time.Millisecond, checks...)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return status() == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	// A failing check makes the service unready again and skips the
+	// remaining checks.
+	failing.Store(true)
+	require.Eventually(t, func() bool {
+		return status() == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 5*time.Millisecond)
+	runs := later.Load()
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, runs, later.Load())
+
+	failing.Store(false)
+	require.Eventually(t, func() bool {
+		return status() == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Monitor did not return after the context was done")
+	}
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		health bool // Whether the server runs the health service.
+		errMsg string
+	}{
+		{"not serving", true, "NOT_SERVING"},
+		{"no health service", false, "Unimplemented"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := grpc.NewServer()
+			if tt.health {
+				readiness.NewServer(s)
+			}
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go s.Serve(lis)
+			defer s.Stop()
+			conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+			defer cancel()
+			err = readiness.WaitReady(ctx, conn)
+			require.ErrorContains(t, err, "not ready")
+			require.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestWaitReadyUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	require.ErrorContains(t, readiness.WaitReady(ctx, conn), "not ready")
+}
+
+func TestNodeCheck(t *testing.T) {
+	chain := &testChain{tip: 100}
+	check := readiness.NodeCheck(chain)
+	require.NoError(t, check(context.Background()))
+	chain.tipErr = errors.New("connection refused")
+	require.ErrorContains(t, check(context.Background()), "node unreachable")
+}
+
+func TestIndexerCheck(t *testing.T) {
+	chain := &testChain{tip: 100, indexed: 95}
+	check := readiness.IndexerCheck(chain)
+	require.NoError(t, check(context.Background()))
+	chain.indexed = 100 - readiness.MaxIndexerLag - 1
+	require.Error(t, check(context.Background()), "indexer lags behind")
+
+	chain = &testChain{tip: 100, nilTip: true}
+	require.ErrorContains(t, readiness.IndexerCheck(chain)(context.Background()), "behind")
+	chain = &testChain{tip: 100, indexErr: errors.New("method not found")}
+	require.ErrorContains(t, readiness.IndexerCheck(chain)(context.Background()), "indexer unreachable")
+	chain = &testChain{tipErr: errors.New("connection refused")}
+	require.ErrorContains(t, readiness.IndexerCheck(chain)(context.Background()), "node unreachable")
+}
+
+type testChain struct {
+	tip, indexed     uint64
+	nilTip           bool // Whether the indexer reports no tip.
+	tipErr, indexErr error
+}
+
+func (c *testChain) GetTipBlockNumber(context.Context) (uint64, error) {
+	return c.tip, c.tipErr
+}
+
+func (c *testChain) GetIndexerTip(context.Context) (*indexer.TipHeader, error) {
+	if c.indexErr != nil || c.nilTip {
+		return nil, c.indexErr
+	}
+	return &indexer.TipHeader{BlockNumber: c.indexed}, nil
+}
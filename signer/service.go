@@ -0,0 +1,170 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/transaction"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/wallet/address"
+)
+
+// The signer service uses the well-known protobuf wrapper types, so that no
+// additional code generation is needed:
+//
+//	service Signer {
+//	  rpc PublicKey(google.protobuf.Empty) returns (google.protobuf.BytesValue);
+//	  rpc SignData(google.protobuf.BytesValue) returns (google.protobuf.BytesValue);
+//	  rpc SignTransaction(google.protobuf.BytesValue) returns (google.protobuf.BytesValue);
+//	}
+//
+// PublicKey returns the compressed public key of the account. Transactions
+// are JSON encoded, as in the channel service API.
+const (
+	serviceName           = "perun_nervos_demo.Signer"
+	publicKeyMethod       = "/" + serviceName + "/PublicKey"
+	signDataMethod        = "/" + serviceName + "/SignData"
+	signTransactionMethod = "/" + serviceName + "/SignTransaction"
+)
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*Signer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "PublicKey", Handler: publicKeyHandler},
+		{MethodName: "SignData", Handler: signDataHandler},
+		{MethodName: "SignTransaction", Handler: signTransactionHandler},
+	},
+}
+
+// RegisterServer serves the signer s at the gRPC server srv.
+func RegisterServer(srv *grpc.Server, s Signer) {
+	srv.RegisterService(&serviceDesc, s)
+}
+
+// unaryHandler returns the handler of a unary method, which decodes the
+// request into a new value returned by newReq and passes it to handle.
+func unaryHandler(method string, newReq func() interface{}, handle func(s Signer, ctx context.Context, req interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := newReq()
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		h := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return handle(srv.(Signer), ctx, req)
+		}
+		if interceptor == nil {
+			return h(ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: method}
+		return interceptor(ctx, req, info, h)
+	}
+}
+
+var publicKeyHandler = unaryHandler(publicKeyMethod,
+	func() interface{} { return new(emptypb.Empty) },
+	func(s Signer, _ context.Context, _ interface{}) (interface{}, error) {
+		return wrapperspb.Bytes(address.AsParticipant(s.Address()).PubKey.SerializeCompressed()), nil
+	})
+
+var signDataHandler = unaryHandler(signDataMethod,
+	func() interface{} { return new(wrapperspb.BytesValue) },
+	func(s Signer, ctx context.Context, req interface{}) (interface{}, error) {
+		sig, err := s.SignData(ctx, req.(*wrapperspb.BytesValue).Value)
+		if err != nil {
+			return nil, fmt.Errorf("signing data: %w", err)
+		}
+		return wrapperspb.Bytes(sig), nil
+	})
+
+var signTransactionHandler = unaryHandler(signTransactionMethod,
+	func() interface{} { return new(wrapperspb.BytesValue) },
+	func(s Signer, ctx context.Context, req interface{}) (interface{}, error) {
+		var tx transaction.TransactionWithScriptGroups
+		if err := json.Unmarshal(req.(*wrapperspb.BytesValue).Value, &tx); err != nil {
+			return nil, fmt.Errorf("decoding transaction: %w", err)
+		}
+		signed, err := s.SignTransaction(ctx, &tx)
+		if err != nil {
+			return nil, fmt.Errorf("signing transaction: %w", err)
+		}
+		data, err := json.Marshal(signed)
+		if err != nil {
+			return nil, fmt.Errorf("encoding transaction: %w", err)
+		}
+		return wrapperspb.Bytes(data), nil
+	})
+
+// Client is a Signer which forwards all requests to a signer daemon.
+type Client struct {
+	conn *grpc.ClientConn
+	addr gpwallet.Address
+}
+
+// Dial connects to the signer daemon listening on the unix socket at path
+// and fetches the address of its account.
+func Dial(ctx context.Context, path string) (*Client, error) {
+	conn, err := grpc.Dial("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dialing signer: %w", err)
+	}
+	pub := new(wrapperspb.BytesValue)
+	if err := conn.Invoke(ctx, publicKeyMethod, new(emptypb.Empty), pub, grpc.WaitForReady(true)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("getting public key of signer: %w", err)
+	}
+	key, err := secp256k1.ParsePubKey(pub.Value)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid public key of signer: %w", err)
+	}
+	addr, err := address.NewDefaultParticipant(key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{conn: conn, addr: addr}, nil
+}
+
+// Address returns the wallet address of the signer's account.
+func (c *Client) Address() gpwallet.Address {
+	return c.addr
+}
+
+// SignData asks the signer daemon to sign data.
+func (c *Client) SignData(ctx context.Context, data []byte) ([]byte, error) {
+	sig := new(wrapperspb.BytesValue)
+	if err := c.conn.Invoke(ctx, signDataMethod, wrapperspb.Bytes(data), sig); err != nil {
+		return nil, err
+	}
+	return sig.Value, nil
+}
+
+// SignTransaction asks the signer daemon to sign the transaction.
+func (c *Client) SignTransaction(ctx context.Context, tx *transaction.TransactionWithScriptGroups) (*types.Transaction, error) {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return nil, fmt.Errorf("encoding transaction: %w", err)
+	}
+	signed := new(wrapperspb.BytesValue)
+	if err := c.conn.Invoke(ctx, signTransactionMethod, wrapperspb.Bytes(data), signed); err != nil {
+		return nil, err
+	}
+	var res types.Transaction
+	if err := json.Unmarshal(signed.Value, &res); err != nil {
+		return nil, fmt.Errorf("decoding signed transaction: %w", err)
+	}
+	return &res, nil
+}
+
+// Close closes the connection to the signer daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
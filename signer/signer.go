@@ -0,0 +1,59 @@
+// Package signer moves the private key of an account out of the wallet
+// service. The wallet service decides what to sign and asks a Signer for the
+// signatures, which is either a LocalSigner holding the key in memory or a
+// Client of a signer daemon, which stands in for an HSM or a hardware wallet.
+package signer
+
+import (
+	"context"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/transaction"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/backend"
+	"perun.network/perun-ckb-backend/wallet"
+	"perun.network/perun-ckb-backend/wallet/address"
+)
+
+// Signer signs on behalf of one account.
+type Signer interface {
+	// Address returns the wallet address of the account.
+	Address() gpwallet.Address
+	// SignData signs data, which is the on-chain encoding of a channel state.
+	SignData(ctx context.Context, data []byte) ([]byte, error)
+	// SignTransaction signs the script groups of the transaction which are
+	// locked by the account.
+	SignTransaction(ctx context.Context, tx *transaction.TransactionWithScriptGroups) (*types.Transaction, error)
+}
+
+// LocalSigner signs with a private key held in memory.
+type LocalSigner struct {
+	account *wallet.Account
+	signer  *backend.LocalSigner
+}
+
+// NewLocalSigner creates a signer for the account of key on the network.
+func NewLocalSigner(key *secp256k1.PrivateKey, network types.Network) *LocalSigner {
+	acc := wallet.NewAccountFromPrivateKey(key)
+	ckbAddr := address.AsParticipant(acc.Address()).ToCKBAddress(network)
+	return &LocalSigner{
+		account: acc,
+		signer:  backend.NewSignerInstance(ckbAddr, *key, network),
+	}
+}
+
+// Address returns the wallet address of the account.
+func (s *LocalSigner) Address() gpwallet.Address {
+	return s.account.Address()
+}
+
+// SignData signs data with the key of the account.
+func (s *LocalSigner) SignData(_ context.Context, data []byte) ([]byte, error) {
+	return s.account.SignData(data)
+}
+
+// SignTransaction signs the transaction with the key of the account.
+func (s *LocalSigner) SignTransaction(_ context.Context, tx *transaction.TransactionWithScriptGroups) (*types.Transaction, error) {
+	return s.signer.SignTransaction(tx)
+}
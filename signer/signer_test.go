@@ -0,0 +1,44 @@
+package signer_test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"perun.network/perun-nervos-demo/signer"
+)
+
+func TestClient(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	local := signer.NewLocalSigner(key, types.NetworkTest)
+
+	path := filepath.Join(t.TempDir(), "signer.sock")
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	signer.RegisterServer(s, local)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := signer.Dial(ctx, path)
+	require.NoError(t, err)
+	defer c.Close()
+	require.True(t, c.Address().Equal(local.Address()))
+
+	// Signatures are deterministic, so the daemon signs as the local signer.
+	data := []byte("channel state")
+	sig, err := c.SignData(ctx, data)
+	require.NoError(t, err)
+	expected, err := local.SignData(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, expected, sig)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/keystore"
+	"perun.network/perun-nervos-demo/signer"
+)
+
+const (
+	// configPath is the default location of the demo configuration.
+	configPath = "../config.yaml"
+)
+
+// SetLogFile sets the log file for the signer service.
+func SetLogFile(path string) {
+	logFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		log.Fatalf("error opening file: %v", err)
+	}
+	log.SetOutput(logFile)
+}
+
+// Start the signers of all participants with a configured signer socket.
+func main() {
+	SetLogFile("signer_service.log")
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	network, err := cfg.CKBNetwork()
+	if err != nil {
+		log.Fatalf("error getting network: %v", err)
+	}
+
+	var servers []*grpc.Server
+	for _, cp := range cfg.Participants {
+		if cp.Signer == "" {
+			continue
+		}
+		key, err := keystore.Unlock(cp.KeyFile, cp.KeyFormat, keystore.PassphraseSource{
+			Env:    config.PassphraseEnv(cp.Name),
+			File:   cp.PassphraseFile,
+			Prompt: fmt.Sprintf("Passphrase of %s's key: ", cp.Name),
+		})
+		if err != nil {
+			log.Fatalf("error getting %s's private key: %v", cp.Name, err)
+		}
+		lis, err := listen(cp.Signer)
+		if err != nil {
+			log.Fatalf("failed to listen: %v", err)
+		}
+		s := grpc.NewServer()
+		signer.RegisterServer(s, signer.NewLocalSigner(key, network))
+		go func(name, path string) {
+			fmt.Printf("Starting %s Signer at %s \n", name, path)
+			if err := s.Serve(lis); err != nil {
+				log.Fatalf("serving signer: %v", err)
+			}
+		}(cp.Name, cp.Signer)
+		servers = append(servers, s)
+	}
+	if len(servers) == 0 {
+		log.Fatalf("no signer configured")
+	}
+
+	// Signal handling for graceful shutdown
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+	fmt.Println("Shutting down signers...")
+	for _, s := range servers {
+		s.Stop()
+	}
+	fmt.Println("Signers stopped.")
+}
+
+// listen listens on the unix socket at path, which only the owner may use.
+// A socket left over by a previous run is removed.
+func listen(path string) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	switch {
+	case err == nil && info.Mode().Type() != fs.ModeSocket:
+		return nil, fmt.Errorf("%s exists and is no socket", path)
+	case err == nil:
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	lis, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		lis.Close()
+		return nil, err
+	}
+	return lis, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"polycry.pt/poly-go/sync"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/rpc"
+	"google.golang.org/grpc/credentials"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/keystore"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/readiness"
+	"perun.network/perun-nervos-demo/signer"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+// walletHosts are the wallet service hosts of the demo, one per configured
+// wallet service address, so that all accounts with the same address share
+// one listener. With mutual TLS, the hosts only accept the channel services
+// authorized for their accounts. The hosts are ready while the node is
+// reachable and its indexer is in sync.
+type walletHosts struct {
+	mtx     sync.Mutex
+	tls     *config.TLS
+	nodeURL string
+	hosts   map[string]*wallet_service.Host
+	wg      sync.WaitGroup
+
+	ctx    context.Context // Ends the readiness checks of the hosts.
+	cancel context.CancelFunc
+}
+
+func newWalletHosts(cfg *config.Config) *walletHosts {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &walletHosts{
+		tls:     cfg.TLS,
+		nodeURL: cfg.NodeURL,
+		hosts:   make(map[string]*wallet_service.Host),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// get returns the host at url, which starts serving on first use.
+func (h *walletHosts) get(url string) (*wallet_service.Host, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if host, ok := h.hosts[url]; ok {
+		return host, nil
+	}
+	var creds credentials.TransportCredentials
+	if h.tls != nil {
+		var err error
+		creds, err = mtls.ServerCredentials(mtls.DirFiles(h.tls.CertDir, mtls.WalletService))
+		if err != nil {
+			return nil, fmt.Errorf("loading wallet service certificate: %w", err)
+		}
+	}
+	chain, err := rpc.Dial(h.nodeURL)
+	if err != nil {
+		return nil, err
+	}
+	host, err := wallet_service.NewHostServer(url, creds, &h.wg)
+	if err != nil {
+		return nil, fmt.Errorf("serving wallet service at %s: %w", url, err)
+	}
+	go host.MonitorReadiness(h.ctx, readiness.DefaultInterval, readiness.NodeCheck(chain), readiness.IndexerCheck(chain))
+	h.hosts[url] = host
+	return host, nil
+}
+
+// shutdown shuts all hosts down and waits until they are stopped.
+func (h *walletHosts) shutdown() {
+	h.cancel()
+	h.mtx.Lock()
+	for _, host := range h.hosts {
+		host.Shutdown(&h.wg)
+	}
+	h.mtx.Unlock()
+	h.wg.Wait()
+}
+
+// hostWalletAccounts hosts the configured wallet accounts until ctx is done,
+// including the keystores which are added to their directory later on. Only
+// the wallet services of the accounts are hosted. The channel service of the
+// demo has no users for them, they serve channel services which are run
+// separately for the accounts.
+func hostWalletAccounts(ctx context.Context, cfg *config.Config, hosts *walletHosts) error {
+	wa := cfg.WalletAccounts
+	shared := keystore.PassphraseSource{
+		Env:  config.PassphraseEnv("wallet_accounts"),
+		File: wa.PassphraseFile,
+	}
+	_, hasEnv := os.LookupEnv(shared.Env)
+	// Keystores may be added at any time, so their passphrase cannot be asked
+	// on the terminal.
+	source := func(name string) (keystore.PassphraseSource, error) {
+		if file, own := wa.PassphraseFileOf(name); own {
+			return keystore.PassphraseSource{File: file}, nil
+		}
+		if !hasEnv && shared.File == "" {
+			return shared, fmt.Errorf("no %s.passphrase, passphrase_file or %s", name, shared.Env)
+		}
+		return shared, nil
+	}
+	network, err := cfg.CKBNetwork()
+	if err != nil {
+		return err
+	}
+	d, err := loadDeployment(cfg)
+	if err != nil {
+		return err
+	}
+	chain, err := rpc.Dial(cfg.NodeURL)
+	if err != nil {
+		return err
+	}
+	host, err := hosts.get(wa.WalletService)
+	if err != nil {
+		return err
+	}
+	bounds := wallet_service.ChallengeDurationBounds{Min: cfg.MinChallengeDuration, Max: cfg.MaxChallengeDuration}
+	open := func(name, path string) (*wallet_service.MyWalletService, error) {
+		if _, ok := cfg.Participant(name); ok {
+			return nil, errors.New("name is already used by a participant")
+		}
+		source, err := source(name)
+		if err != nil {
+			return nil, err
+		}
+		key, err := keystore.Unlock(path, keystore.FormatKeystore, source)
+		if err != nil {
+			return nil, err
+		}
+		var dbDir string
+		if wa.DBDir != "" {
+			dbDir = filepath.Join(wa.DBDir, name)
+		}
+		ws, err := wallet_service.NewWalletService(name, signer.NewLocalSigner(key, network), network, dbDir, bounds, d, chain)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.TLS != nil {
+			ws.SetAuthorizedCaller(mtls.ChannelServiceOf(name))
+		}
+		return ws, nil
+	}
+	go host.WatchDir(ctx, wa.Dir, wallet_service.DefaultDirPollInterval, open)
+	return nil
+}
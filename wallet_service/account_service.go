@@ -0,0 +1,403 @@
+package wallet_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+)
+
+// accountService serves the account API, see package walletpb, of the
+// accounts of a host next to the wallet service API.
+type accountService struct {
+	h *Host
+
+	walletpb.UnimplementedWalletAccountServer
+}
+
+// account returns the hosted account with the given binary encoded address if
+// the caller is authorized for it.
+func (s *accountService) account(ctx context.Context, account []byte) (*MyWalletService, error) {
+	var addr address.Participant
+	if err := addr.UnmarshalBinary(account); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid account: %v", err)
+	}
+	ws := s.h.Account(&addr)
+	if ws == nil {
+		return nil, status.Errorf(codes.NotFound, "account %v is not hosted", &addr)
+	}
+	if err := ws.authorizeClient(ctx); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// Subscribe sends the events of the account until the stream is done.
+func (s *accountService) Subscribe(req *walletpb.AccountRequest, stream walletpb.WalletAccount_SubscribeServer) error {
+	ws, err := s.account(stream.Context(), req.Account)
+	if err != nil {
+		return err
+	}
+	events, snapshot, cancel := ws.subscribe()
+	defer cancel()
+	for _, e := range snapshot {
+		if err := stream.Send(e); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber lags behind")
+			}
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// GetChannels returns the latest states of the open channels of the account.
+func (s *accountService) GetChannels(ctx context.Context, req *walletpb.AccountRequest) (*walletpb.ChannelsResponse, error) {
+	ws, err := s.account(ctx, req.Account)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(walletpb.ChannelsResponse)
+	for _, state := range ws.Channels() {
+		protoState, err := protobuf.FromState(state)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "encoding state of channel %x: %v", state.ID, err)
+		}
+		parts, err := encodeAddresses(ws.Participants(state.ID))
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "encoding participants of channel %x: %v", state.ID, err)
+		}
+		resp.Channels = append(resp.Channels, &walletpb.Channel{State: protoState, Participants: parts})
+	}
+	return resp, nil
+}
+
+// GetHistory returns the states of a channel of the account on which both
+// participants agreed.
+func (s *accountService) GetHistory(ctx context.Context, req *walletpb.ChannelRequest) (*walletpb.HistoryResponse, error) {
+	ws, err := s.account(ctx, req.Account)
+	if err != nil {
+		return nil, err
+	}
+	id, err := channelID(req.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+	states, err := ws.History(id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "reading history: %v", err)
+	}
+	resp := new(walletpb.HistoryResponse)
+	for _, state := range states {
+		protoState, err := protobuf.FromState(state)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "encoding state: %v", err)
+		}
+		resp.States = append(resp.States, protoState)
+	}
+	return resp, nil
+}
+
+// AuthorizeProposal authorizes the initial state of a channel which the
+// client proposes, see MyWalletService.AuthorizeProposal.
+func (s *accountService) AuthorizeProposal(ctx context.Context, req *walletpb.AuthorizeProposalRequest) (*walletpb.Authorization, error) {
+	ws, err := s.account(ctx, req.Account)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := decodeAddresses(req.Participants)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid participants: %v", err)
+	}
+	if req.Allocation == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing allocation")
+	}
+	alloc, err := toCKBAllocation(req.Allocation, ws.sudts)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid allocation: %v", err)
+	}
+	revoke, err := ws.AuthorizeProposal(parts, alloc)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return &walletpb.Authorization{Id: ws.addAuthorization(revoke)}, nil
+}
+
+// AuthorizeUpdate authorizes an update which the client proposes, see
+// MyWalletService.AuthorizeUpdate.
+func (s *accountService) AuthorizeUpdate(ctx context.Context, req *walletpb.AuthorizeUpdateRequest) (*walletpb.Authorization, error) {
+	ws, err := s.account(ctx, req.Account)
+	if err != nil {
+		return nil, err
+	}
+	if req.State == nil || req.State.Allocation == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing state")
+	}
+	state, err := toCKBState(req.State, ws.sudts)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid state: %v", err)
+	}
+	revoke, err := ws.AuthorizeUpdate(state)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return &walletpb.Authorization{Id: ws.addAuthorization(revoke)}, nil
+}
+
+// Revoke revokes an authorization of the account. Revoking an unknown
+// authorization does nothing.
+func (s *accountService) Revoke(ctx context.Context, req *walletpb.RevokeRequest) (*walletpb.RevokeResponse, error) {
+	ws, err := s.account(ctx, req.Account)
+	if err != nil {
+		return nil, err
+	}
+	ws.revokeAuthorization(req.Id)
+	return new(walletpb.RevokeResponse), nil
+}
+
+// CloseChannel reports that the client settled a channel of the account, see
+// MyWalletService.CloseChannel.
+func (s *accountService) CloseChannel(ctx context.Context, req *walletpb.ChannelRequest) (*walletpb.CloseChannelResponse, error) {
+	ws, err := s.account(ctx, req.Account)
+	if err != nil {
+		return nil, err
+	}
+	id, err := channelID(req.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.CloseChannel(id); errors.Is(err, errUnknownChannel) {
+		return nil, status.Errorf(codes.NotFound, "%v", err)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	return new(walletpb.CloseChannelResponse), nil
+}
+
+// addAuthorization keeps the revocation of an authorization of the client and
+// returns its identifier.
+func (wsc *MyWalletService) addAuthorization(revoke func()) uint64 {
+	wsc.authMtx.Lock()
+	defer wsc.authMtx.Unlock()
+	if wsc.authorizations == nil {
+		wsc.authorizations = make(map[uint64]func())
+	}
+	wsc.nextAuth++
+	wsc.authorizations[wsc.nextAuth] = revoke
+	return wsc.nextAuth
+}
+
+// revokeAuthorization revokes the authorization of the client with the given
+// identifier.
+func (wsc *MyWalletService) revokeAuthorization(id uint64) {
+	wsc.authMtx.Lock()
+	revoke, ok := wsc.authorizations[id]
+	delete(wsc.authorizations, id)
+	wsc.authMtx.Unlock()
+	if ok {
+		revoke()
+	}
+}
+
+func channelID(b []byte) (channel.ID, error) {
+	var id channel.ID
+	if len(b) != len(id) {
+		return id, status.Errorf(codes.InvalidArgument, "invalid channel ID of length %d", len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// encodeAddresses returns the binary encodings of addrs.
+func encodeAddresses(addrs []gpwallet.Address) ([][]byte, error) {
+	encoded := make([][]byte, len(addrs))
+	for i, addr := range addrs {
+		b, err := addr.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = b
+	}
+	return encoded, nil
+}
+
+// decodeAddresses decodes the binary encoded participant addresses.
+func decodeAddresses(encoded [][]byte) ([]gpwallet.Address, error) {
+	addrs := make([]gpwallet.Address, len(encoded))
+	for i, b := range encoded {
+		addr := new(address.Participant)
+		if err := addr.UnmarshalBinary(b); err != nil {
+			return nil, fmt.Errorf("%d'th address: %w", i, err)
+		}
+		addrs[i] = addr
+	}
+	return addrs, nil
+}
+
+// AccountClient is the client of the account API of a wallet service for one
+// account.
+type AccountClient struct {
+	api     walletpb.WalletAccountClient
+	account []byte
+}
+
+// NewAccountClient creates a client of the account API served at cc for the
+// given account.
+func NewAccountClient(cc grpc.ClientConnInterface, account gpwallet.Address) (*AccountClient, error) {
+	b, err := account.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("encoding account: %w", err)
+	}
+	return &AccountClient{api: walletpb.NewWalletAccountClient(cc), account: b}, nil
+}
+
+// Subscribe calls handle with every event of the account until ctx is done or
+// the stream fails. The first events are updates of the open channels.
+func (c *AccountClient) Subscribe(ctx context.Context, handle func(*walletpb.Event)) error {
+	stream, err := c.api.Subscribe(ctx, &walletpb.AccountRequest{Account: c.account})
+	if err != nil {
+		return err
+	}
+	for {
+		e, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		handle(e)
+	}
+}
+
+// Channel is the latest state of an open channel and its participants in
+// channel order.
+type Channel struct {
+	State        *channel.State
+	Participants []gpwallet.Address
+}
+
+// Channels returns the open channels of the account.
+func (c *AccountClient) Channels(ctx context.Context) ([]Channel, error) {
+	resp, err := c.api.GetChannels(ctx, &walletpb.AccountRequest{Account: c.account})
+	if err != nil {
+		return nil, err
+	}
+	chs := make([]Channel, 0, len(resp.Channels))
+	for _, ch := range resp.Channels {
+		state, err := protobuf.ToState(ch.State)
+		if err != nil {
+			return nil, fmt.Errorf("decoding state: %w", err)
+		}
+		parts, err := decodeAddresses(ch.Participants)
+		if err != nil {
+			return nil, fmt.Errorf("decoding participants of channel %x: %w", state.ID, err)
+		}
+		chs = append(chs, Channel{State: state, Participants: parts})
+	}
+	return chs, nil
+}
+
+// History returns all states of the channel with the given ID on which both
+// participants agreed, ordered by version.
+func (c *AccountClient) History(ctx context.Context, id channel.ID) ([]*channel.State, error) {
+	resp, err := c.api.GetHistory(ctx, &walletpb.ChannelRequest{Account: c.account, ChannelId: id[:]})
+	if err != nil {
+		return nil, err
+	}
+	states := make([]*channel.State, len(resp.States))
+	for i, protoState := range resp.States {
+		if states[i], err = protobuf.ToState(protoState); err != nil {
+			return nil, fmt.Errorf("decoding state: %w", err)
+		}
+	}
+	return states, nil
+}
+
+// AuthorizeProposal allows the wallet service to sign the initial state of the
+// channel with the given participants and initial allocation, which we
+// propose, until the returned function is called.
+func (c *AccountClient) AuthorizeProposal(ctx context.Context, parts []gpwallet.Address, alloc *channel.Allocation) (revoke func(), err error) {
+	encoded, err := encodeAddresses(parts)
+	if err != nil {
+		return nil, fmt.Errorf("encoding participants: %w", err)
+	}
+	protoAlloc, err := protobuf.FromAllocation(*alloc)
+	if err != nil {
+		return nil, fmt.Errorf("encoding allocation: %w", err)
+	}
+	auth, err := c.api.AuthorizeProposal(ctx, &walletpb.AuthorizeProposalRequest{
+		Account:      c.account,
+		Participants: encoded,
+		Allocation:   protoAlloc,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.revoker(auth.Id), nil
+}
+
+// AuthorizeUpdate allows the wallet service to sign the given update, which we
+// propose, until the returned function is called.
+func (c *AccountClient) AuthorizeUpdate(ctx context.Context, state *channel.State) (revoke func(), err error) {
+	protoState, err := protobuf.FromState(state)
+	if err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+	auth, err := c.api.AuthorizeUpdate(ctx, &walletpb.AuthorizeUpdateRequest{Account: c.account, State: protoState})
+	if err != nil {
+		return nil, err
+	}
+	return c.revoker(auth.Id), nil
+}
+
+// revoker returns a function which revokes the authorization with the given
+// identifier.
+func (c *AccountClient) revoker(id uint64) func() {
+	return func() {
+		_, err := c.api.Revoke(context.Background(), &walletpb.RevokeRequest{Account: c.account, Id: id})
+		if err != nil {
+			log.Printf("Revoking authorization %d: %v", id, err)
+		}
+	}
+}
+
+// CloseChannel tells the wallet service that we settled the channel with the
+// given ID. The wallet service publishes a closed event once it observes the
+// settlement on-chain.
+func (c *AccountClient) CloseChannel(ctx context.Context, id channel.ID) error {
+	_, err := c.api.CloseChannel(ctx, &walletpb.ChannelRequest{Account: c.account, ChannelId: id[:]})
+	return err
+}
+
+// EventState returns the state of an update or closed event.
+func EventState(e *walletpb.Event) (*channel.State, error) {
+	if e.State == nil {
+		return nil, errors.New("event without state")
+	}
+	return protobuf.ToState(e.State)
+}
+
+// EventParticipants returns the participants of the channel of an update or
+// closed event in channel order.
+func EventParticipants(e *walletpb.Event) ([]gpwallet.Address, error) {
+	return decodeAddresses(e.Participants)
+}
@@ -0,0 +1,77 @@
+package wallet_service
+
+import (
+	"context"
+	"time"
+
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+)
+
+// DefaultApprovalTimeout is the time an Approver has to decide on a proposal
+// if no other timeout is set.
+const DefaultApprovalTimeout = time.Minute
+
+// Proposal is an incoming channel proposal which awaits approval. We are
+// always the participant with index 1 in the allocation.
+type Proposal struct {
+	Peer              gpwallet.Address
+	Allocation        *channel.Allocation
+	ChallengeDuration uint64 // In seconds.
+}
+
+// Decision is the answer to a channel proposal.
+type Decision struct {
+	Accept bool
+	Reason string // Why the proposal is rejected.
+}
+
+// Accept accepts a proposal.
+func Accept() Decision {
+	return Decision{Accept: true}
+}
+
+// Reject rejects a proposal for the given reason.
+func Reject(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+// Approver decides whether to accept an incoming channel proposal, e.g., by
+// asking the user or by checking a set of rules. The proposal is rejected if
+// the approver does not decide before ctx is done.
+type Approver func(ctx context.Context, prop *Proposal) Decision
+
+// SetApprover sets the approver of incoming channel proposals and the time it
+// has to decide. Without an approver, all valid proposals are accepted.
+func (wsc *MyWalletService) SetApprover(approver Approver, timeout time.Duration) {
+	wsc.approvalMtx.Lock()
+	defer wsc.approvalMtx.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	wsc.approver = approver
+	wsc.approvalTimeout = timeout
+}
+
+// approve asks the approver to decide on the proposal.
+func (wsc *MyWalletService) approve(ctx context.Context, prop *Proposal) Decision {
+	wsc.approvalMtx.Lock()
+	approver, timeout := wsc.approver, wsc.approvalTimeout
+	wsc.approvalMtx.Unlock()
+	if approver == nil {
+		return Accept()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	decision := make(chan Decision, 1)
+	go func() {
+		decision <- approver(ctx, prop)
+	}()
+	select {
+	case d := <-decision:
+		return d
+	case <-ctx.Done():
+		return Reject("Proposal was not approved in time")
+	}
+}
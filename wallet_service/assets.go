@@ -0,0 +1,157 @@
+package wallet_service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/perun-ckb-backend/channel/asset"
+	molecule2 "perun.network/perun-ckb-backend/encoding/molecule"
+)
+
+// Chain is the part of a CKB node which the wallet service queries. It is
+// implemented by rpc.Client.
+type Chain interface {
+	ChainReader
+	GetCells(ctx context.Context, searchKey *indexer.SearchKey, order indexer.SearchOrder, limit uint64, afterCursor string) (*indexer.LiveCells, error)
+	GetTransactions(ctx context.Context, searchKey *indexer.SearchKey, order indexer.SearchOrder, limit uint64, afterCursor string) (*indexer.TxsWithCell, error)
+}
+
+// AssetBalance is the on-chain balance of the wallet in one asset.
+type AssetBalance struct {
+	TypeScript *types.Script // The SUDT type script or nil for CKBytes.
+	Balance    *big.Int      // In Shannon for CKBytes.
+}
+
+// Name returns a human readable name of the asset.
+func (a AssetBalance) Name() string {
+	if a.TypeScript == nil {
+		return "CKBytes"
+	}
+	return fmt.Sprintf("SUDT %v", a.TypeScript.Hash())
+}
+
+// sortedSUDTs returns the SUDT type scripts of a deployment ordered by their
+// hash, which fixes the index of every asset.
+func sortedSUDTs(sudts map[types.Hash]types.Script) []types.Script {
+	hashes := make([]types.Hash, 0, len(sudts))
+	for hash := range sudts {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	scripts := make([]types.Script, len(hashes))
+	for i, hash := range hashes {
+		scripts[i] = sudts[hash]
+	}
+	return scripts
+}
+
+// decodeAsset decodes a serialized asset.Asset and checks that it is CKBytes or
+// one of the given SUDTs.
+func decodeAsset(data []byte, sudts []types.Script) (*asset.Asset, error) {
+	a := asset.NewInvalidAsset()
+	if err := a.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if a.IsCKBytes {
+		return a, nil
+	}
+	for i := range sudts {
+		if a.SUDT.TypeScript.Equals(&sudts[i]) {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown SUDT %v", a.SUDT.TypeScript.Hash())
+}
+
+// Assets returns the assets supported by the wallet together with its current
+// on-chain balances. The first asset is CKBytes, followed by the SUDTs of the
+// deployment.
+func (wsc *MyWalletService) Assets(ctx context.Context) ([]AssetBalance, error) {
+	searchKey := &indexer.SearchKey{
+		Script:           wsc.ownLock,
+		ScriptType:       types.ScriptTypeLock,
+		ScriptSearchMode: types.ScriptSearchModeExact,
+		Filter:           nil,
+		WithData:         true,
+	}
+	cells, err := wsc.chain.GetCells(ctx, searchKey, indexer.SearchOrderDesc, math.MaxUint32, "")
+	if err != nil {
+		return nil, fmt.Errorf("querying cells: %w", err)
+	}
+	return sumBalances(cells.Objects, wsc.sudts), nil
+}
+
+// sumBalances sums the CKBytes and the given SUDTs held in cells.
+func sumBalances(cells []*indexer.LiveCell, sudts []types.Script) []AssetBalance {
+	assets := make([]AssetBalance, len(sudts)+1)
+	assets[0] = AssetBalance{Balance: big.NewInt(0)}
+	for i := range sudts {
+		assets[i+1] = AssetBalance{TypeScript: &sudts[i], Balance: big.NewInt(0)}
+	}
+	for _, cell := range cells {
+		assets[0].Balance.Add(assets[0].Balance, new(big.Int).SetUint64(cell.Output.Capacity))
+		if cell.Output.Type == nil {
+			continue
+		}
+		for i := range sudts {
+			if !cell.Output.Type.Equals(&sudts[i]) {
+				continue
+			}
+			// The amount is stored in the first 16 bytes of the cell data.
+			if len(cell.OutputData) < 16 {
+				break
+			}
+			amount := molecule.Uint128FromSliceUnchecked(cell.OutputData[:16])
+			assets[i+1].Balance.Add(assets[i+1].Balance, molecule2.UnpackUint128(amount).Big())
+			break
+		}
+	}
+	return assets
+}
+
+// GetAssets checks that the wallet supports and holds the requested assets.
+// The i-th requested balance refers to the i-th asset returned by Assets and
+// the wallet has to hold at least the sum of its entries on-chain.
+func (wsc *MyWalletService) GetAssets(ctx context.Context, in *proto.GetAssetsRequest) (*proto.GetAssetsResponse, error) {
+	assets, err := wsc.Assets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assets {
+		wsc.logger.Printf("GetAssets: %s balance %v", a.Name(), a.Balance)
+	}
+	for i, bal := range in.GetAssets().GetBalances() {
+		if i >= len(assets) {
+			return getAssetsRejected(i, fmt.Sprintf("Asset %d is not supported", i)), nil
+		}
+		requested := big.NewInt(0)
+		for _, b := range bal.GetBalance() {
+			requested.Add(requested, new(big.Int).SetBytes(b))
+		}
+		if assets[i].Balance.Cmp(requested) < 0 {
+			return getAssetsRejected(i, fmt.Sprintf("Balance of %s is %v but %v are requested", assets[i].Name(), assets[i].Balance, requested)), nil
+		}
+	}
+	return &proto.GetAssetsResponse{}, nil
+}
+
+func getAssetsRejected(idx int, reason string) *proto.GetAssetsResponse {
+	return &proto.GetAssetsResponse{
+		Msg: &proto.GetAssetsResponse_Rejected{
+			Rejected: &proto.UnmatchableAssetsResponse{
+				AssetIdx: uint32(idx),
+				Reason:   reason,
+			},
+		},
+	}
+}
@@ -0,0 +1,87 @@
+package wallet_service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-ckb-backend/channel/asset"
+)
+
+func TestSumBalances(t *testing.T) {
+	sudtA := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData1, Args: []byte{1}}
+	sudtB := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData1, Args: []byte{2}}
+	other := types.Script{CodeHash: types.Hash{2}, HashType: types.HashTypeData1}
+	sudts := sortedSUDTs(map[types.Hash]types.Script{sudtA.Hash(): sudtA, sudtB.Hash(): sudtB})
+
+	amount := func(a uint64) []byte {
+		data := make([]byte, 16)
+		new(big.Int).SetUint64(a).FillBytes(data)
+		// SUDT amounts are little endian.
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return data
+	}
+	cell := func(capacity uint64, typ *types.Script, data []byte) *indexer.LiveCell {
+		return &indexer.LiveCell{Output: &types.CellOutput{Capacity: capacity, Type: typ}, OutputData: data}
+	}
+	assets := sumBalances([]*indexer.LiveCell{
+		cell(100, nil, nil),
+		cell(200, &sudtA, amount(5)),
+		cell(300, &sudtA, append(amount(7), 0xff)), // Extra data is ignored.
+		cell(400, &sudtB, amount(1)),
+		cell(500, &other, amount(9)),
+	}, sudts)
+
+	require.Len(t, assets, 3)
+	require.Nil(t, assets[0].TypeScript)
+	require.Equal(t, "1500", assets[0].Balance.String())
+	for _, a := range assets[1:] {
+		want := "12"
+		if !a.TypeScript.Equals(&sudtA) {
+			want = "1"
+		}
+		require.Equal(t, want, a.Balance.String(), a.Name())
+	}
+}
+
+func TestToCKBAllocation(t *testing.T) {
+	sudtA := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData1, Args: []byte{1}}
+	sudtB := types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData1, Args: []byte{2}}
+	sudts := []types.Script{sudtA, sudtB}
+	ckb := asset.NewCKBytesAsset()
+	a := asset.NewSUDTAsset(asset.NewSUDT(sudtA, 142*100_000_000))
+	b := asset.NewSUDTAsset(asset.NewSUDT(sudtB, 142*100_000_000))
+
+	for _, assets := range [][]channel.Asset{
+		{ckb, a},
+		{a, ckb},
+		{a},
+		{b, a},
+		{b, ckb, a},
+	} {
+		alloc := channel.NewAllocation(2, assets...)
+		for i := range assets {
+			alloc.Balances[i] = []channel.Bal{big.NewInt(int64(i + 1)), big.NewInt(int64(i + 10))}
+		}
+		protoAlloc, err := protobuf.FromAllocation(*alloc)
+		require.NoError(t, err)
+		decoded, err := toCKBAllocation(protoAlloc, sudts)
+		require.NoError(t, err)
+		require.NoError(t, decoded.Equal(alloc))
+		for i, a := range decoded.Assets {
+			require.True(t, a.Equal(assets[i]), "asset %d", i)
+		}
+	}
+
+	// SUDTs which are not in the deployment are rejected.
+	protoAlloc, err := protobuf.FromAllocation(*channel.NewAllocation(2, ckb, b))
+	require.NoError(t, err)
+	_, err = toCKBAllocation(protoAlloc, sudts[:1])
+	require.ErrorContains(t, err, "1'th asset: unknown SUDT")
+}
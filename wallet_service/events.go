@@ -0,0 +1,146 @@
+package wallet_service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+	"polycry.pt/poly-go/sync"
+)
+
+// eventBufferSize is the number of events which a subscriber may lag behind
+// before it is dropped.
+const eventBufferSize = 64
+
+// eventFeed distributes the events of an account to its subscribers.
+type eventFeed struct {
+	mtx  sync.Mutex
+	subs map[chan *walletpb.Event]struct{}
+}
+
+// subscribe returns a channel of all future events and a function which ends
+// the subscription. The channel is closed when the subscription ends, also if
+// the subscriber does not keep up with the events.
+func (f *eventFeed) subscribe() (<-chan *walletpb.Event, func()) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[chan *walletpb.Event]struct{})
+	}
+	sub := make(chan *walletpb.Event, eventBufferSize)
+	f.subs[sub] = struct{}{}
+	return sub, func() {
+		f.mtx.Lock()
+		defer f.mtx.Unlock()
+		f.remove(sub)
+	}
+}
+
+// publish sends e to all subscribers. It never blocks, subscribers which lag
+// behind are dropped instead, so that they notice the missing events.
+func (f *eventFeed) publish(e *walletpb.Event) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for sub := range f.subs {
+		select {
+		case sub <- e:
+		default:
+			f.remove(sub)
+		}
+	}
+}
+
+func (f *eventFeed) remove(sub chan *walletpb.Event) {
+	if _, ok := f.subs[sub]; ok {
+		delete(f.subs, sub)
+		close(sub)
+	}
+}
+
+// publish sends the event to the subscribers of the account.
+func (wsc *MyWalletService) publish(e *walletpb.Event) {
+	e.Time = timestamppb.Now()
+	wsc.events.publish(e)
+}
+
+// updateEvent returns the event of the update from the state from, which may
+// be nil, to the state to. It names the participants of the channel, so that
+// subscribers can follow channels which are new to them.
+func (wsc *MyWalletService) updateEvent(from, to *channel.State) (*walletpb.Event, error) {
+	protoState, err := protobuf.FromState(to)
+	if err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+	parts, err := encodeAddresses(wsc.Participants(to.ID))
+	if err != nil {
+		return nil, fmt.Errorf("encoding participants: %w", err)
+	}
+	e := &walletpb.Event{
+		Type:         walletpb.EventType_EVENT_TYPE_UPDATE,
+		ChannelId:    append([]byte(nil), to.ID[:]...),
+		Version:      to.Version,
+		State:        protoState,
+		Participants: parts,
+	}
+	if from == nil {
+		return e, nil
+	}
+	idx, err := wsc.ownIndex(to.ID)
+	if err != nil {
+		return e, nil
+	}
+	for a := range to.Balances {
+		if a >= len(from.Balances) {
+			break
+		}
+		delta := new(big.Int).Sub(to.Balances[a][idx], from.Balances[a][idx])
+		e.Delta = append(e.Delta, delta.String())
+	}
+	return e, nil
+}
+
+// subscribe subscribes to the events of the account. The returned snapshot
+// contains an update event for the latest state of every open channel, which
+// may be repeated by the first events.
+func (wsc *MyWalletService) subscribe() (<-chan *walletpb.Event, []*walletpb.Event, func()) {
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	events, cancel := wsc.events.subscribe()
+	snapshot := make([]*walletpb.Event, 0, len(wsc.states))
+	for _, state := range wsc.states {
+		e, err := wsc.updateEvent(nil, state)
+		if err != nil {
+			wsc.logger.Printf("Error encoding state of channel %x: %v", state.ID, err)
+			continue
+		}
+		e.Time = timestamppb.Now()
+		snapshot = append(snapshot, e)
+	}
+	return events, snapshot, cancel
+}
+
+// SetAuthorizedClient restricts the requests to the account API, see package
+// walletpb, to callers with the given mutual TLS identity. It must be set
+// before the wallet service receives requests. By default, the authorized
+// caller of the account may use it, see SetAuthorizedCaller.
+func (wsc *MyWalletService) SetAuthorizedClient(identity string) {
+	wsc.client = identity
+}
+
+// authorizeClient returns an error if the caller of a request to the account
+// API is not authorized for the account.
+func (wsc *MyWalletService) authorizeClient(ctx context.Context) error {
+	if wsc.client == "" {
+		return wsc.authorize(ctx)
+	}
+	if err := mtls.Authorize(ctx, wsc.client); err != nil {
+		wsc.logger.Println("Refusing client request:", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package wallet_service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ckbaddress "github.com/nervosnetwork/ckb-sdk-go/v2/address"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/readiness"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+	"polycry.pt/poly-go/sync"
+)
+
+// accountHeader is the gRPC metadata key which names the account a request is
+// meant for, see AccountInterceptor.
+const accountHeader = "perun-account"
+
+// DefaultDirPollInterval is the interval in which a Host looks for new
+// keystores in a directory.
+const DefaultDirPollInterval = 5 * time.Second
+
+// Host serves the wallet services of many accounts at one address. Every
+// request is routed to the account it is meant for: signing requests by the
+// address they contain, update notifications by the participants of the
+// channel and the other requests by the account header of the caller. Only
+// the caller authorized for an account may use it, see
+// MyWalletService.SetAuthorizedCaller. Next to it, the host serves the
+// account API for the clients of the accounts, see package walletpb. Its
+// readiness is reported by the standard gRPC health service, see
+// MonitorReadiness.
+type Host struct {
+	mtx      sync.Mutex
+	accounts map[gpwallet.AddrKey]*MyWalletService
+	server   *grpc.Server
+	health   *health.Server
+
+	proto.UnimplementedWalletServiceServer
+}
+
+// NewHost creates a host without accounts.
+func NewHost() *Host {
+	return &Host{accounts: make(map[gpwallet.AddrKey]*MyWalletService)}
+}
+
+// NewHostServer creates a host and serves it at url. The connections are
+// secured with creds, or unencrypted if creds is nil.
+func NewHostServer(url string, creds credentials.TransportCredentials, wg *sync.WaitGroup) (*Host, error) {
+	lis, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, err
+	}
+	h := NewHost()
+	var opts []grpc.ServerOption
+	if creds != nil {
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(opts...)
+	proto.RegisterWalletServiceServer(s, h)
+	walletpb.RegisterWalletAccountServer(s, &accountService{h: h})
+	h.health = readiness.NewServer(s)
+	go func() {
+		log.Println("wallet service listening on", url)
+		if err := s.Serve(lis); err != nil {
+			panic(err)
+		}
+	}()
+	h.server = s
+	wg.Add(1)
+	return h, nil
+}
+
+// Add starts hosting the wallet service of an account. Requests to the
+// account are routed to ws from now on.
+func (h *Host) Add(ws *MyWalletService) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	key := gpwallet.Key(ws.signer.Address())
+	if _, ok := h.accounts[key]; ok {
+		return fmt.Errorf("account %v is already hosted", ws.signer.Address())
+	}
+	h.accounts[key] = ws
+	return nil
+}
+
+// Account returns the wallet service of the account with the given address,
+// or nil if the account is not hosted.
+func (h *Host) Account(addr gpwallet.Address) *MyWalletService {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.accounts[gpwallet.Key(addr)]
+}
+
+// MonitorReadiness reports the host as ready while all checks pass, until ctx
+// is done. The host is not ready before.
+func (h *Host) MonitorReadiness(ctx context.Context, interval time.Duration, checks ...readiness.Check) {
+	if h.health == nil {
+		return
+	}
+	readiness.Monitor(ctx, h.health, "wallet service", interval, checks...)
+}
+
+// Shutdown stops serving and closes the wallet services of all accounts.
+func (h *Host) Shutdown(wg *sync.WaitGroup) {
+	defer wg.Done()
+	log.Println("Shutting down wallet service...")
+	if h.health != nil {
+		h.health.Shutdown()
+	}
+	if h.server != nil {
+		h.server.Stop()
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for _, ws := range h.accounts {
+		ws.Close()
+	}
+	log.Println("Wallet service stopped gracefully")
+}
+
+// WatchDir hosts an account for every keystore file "<name>.json" in dir,
+// also for the keystores which are added later on, until ctx is done. The
+// directory is checked every interval and open creates the wallet service of
+// a new keystore. Files which could not be opened are tried again once they
+// change. Removing a keystore does not remove its account.
+func (h *Host) WatchDir(ctx context.Context, dir string, interval time.Duration, open func(name, path string) (*MyWalletService, error)) {
+	hosted := make(map[string]bool)
+	failed := make(map[string]time.Time) // Modification times of failed files.
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			log.Printf("Error listing keystores in %s: %v", dir, err)
+		}
+		for _, path := range paths {
+			if hosted[path] {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if mod, ok := failed[path]; ok && mod.Equal(info.ModTime()) {
+				continue
+			}
+			name := strings.TrimSuffix(filepath.Base(path), ".json")
+			if err := h.addKeystore(name, path, open); err != nil {
+				log.Printf("Error hosting account %s: %v", name, err)
+				failed[path] = info.ModTime()
+				continue
+			}
+			log.Printf("Hosting account %s", name)
+			delete(failed, path)
+			hosted[path] = true
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// addKeystore opens the wallet service of a keystore and hosts it.
+func (h *Host) addKeystore(name, path string, open func(name, path string) (*MyWalletService, error)) error {
+	ws, err := open(name, path)
+	if err != nil {
+		return err
+	}
+	if err := h.Add(ws); err != nil {
+		ws.Close()
+		return err
+	}
+	return nil
+}
+
+// AccountInterceptor returns a client interceptor which names the account
+// with the given address in every request, so that a Host can route the
+// requests which contain no address.
+func AccountInterceptor(addr gpwallet.Address) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if b, err := addr.MarshalBinary(); err == nil {
+			ctx = metadata.AppendToOutgoingContext(ctx, accountHeader, hex.EncodeToString(b))
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// OpenChannel routes the proposal to the account named by the caller.
+func (h *Host) OpenChannel(ctx context.Context, in *proto.OpenChannelRequest) (*proto.OpenChannelResponse, error) {
+	ws, err := h.byHeader(ctx)
+	if err != nil {
+		return openChannelRejected(err.Error()), nil
+	}
+	if err := ws.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return ws.OpenChannel(ctx, in)
+}
+
+// UpdateNotification routes the notification to the hosted participant of
+// the channel.
+func (h *Host) UpdateNotification(ctx context.Context, in *proto.UpdateNotificationRequest) (*proto.UpdateNotificationResponse, error) {
+	var id channel.ID
+	copy(id[:], in.GetState().GetId())
+	ws, err := h.byChannel(ctx, id)
+	if err != nil {
+		log.Println("Rejecting update:", err)
+		return &proto.UpdateNotificationResponse{Accepted: false}, nil
+	}
+	if err := ws.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return ws.UpdateNotification(ctx, in)
+}
+
+// SignMessage routes the request to the account of its encoded CKB address.
+func (h *Host) SignMessage(ctx context.Context, in *proto.SignMessageRequest) (*proto.SignMessageResponse, error) {
+	ws, err := h.byCKBAddress(string(in.Pubkey))
+	if err != nil {
+		return &proto.SignMessageResponse{
+			Msg: &proto.SignMessageResponse_Rejected{
+				Rejected: &proto.Rejected{Reason: err.Error()},
+			},
+		}, nil
+	}
+	if err := ws.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return ws.SignMessage(ctx, in)
+}
+
+// SignTransaction routes the request to the account of its identifier, which
+// is the JSON encoded lock script of the account.
+func (h *Host) SignTransaction(ctx context.Context, in *proto.SignTransactionRequest) (*proto.SignTransactionResponse, error) {
+	var lock types.Script
+	err := json.Unmarshal(in.Identifier, &lock)
+	var ws *MyWalletService
+	if err == nil {
+		ws, err = h.byLock(&lock)
+	}
+	if err != nil {
+		return &proto.SignTransactionResponse{
+			Msg: &proto.SignTransactionResponse_Rejected{
+				Rejected: &proto.Rejected{Reason: err.Error()},
+			},
+		}, nil
+	}
+	if err := ws.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return ws.SignTransaction(ctx, in)
+}
+
+// GetAssets routes the request to the account named by the caller.
+func (h *Host) GetAssets(ctx context.Context, in *proto.GetAssetsRequest) (*proto.GetAssetsResponse, error) {
+	ws, err := h.byHeader(ctx)
+	if err != nil {
+		return getAssetsRejected(0, err.Error()), nil
+	}
+	if err := ws.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return ws.GetAssets(ctx, in)
+}
+
+// authorize returns an error if the caller of a request is not authorized for
+// the account of wsc.
+func (wsc *MyWalletService) authorize(ctx context.Context) error {
+	if wsc.caller == "" {
+		return nil
+	}
+	if err := mtls.Authorize(ctx, wsc.caller); err != nil {
+		wsc.logger.Println("Refusing request:", err)
+		return err
+	}
+	return nil
+}
+
+// byHeader returns the account named in the metadata of ctx. Without a name,
+// the only hosted account is returned.
+func (h *Host) byHeader(ctx context.Context) (*MyWalletService, error) {
+	addr, ok, err := headerAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if ok {
+		if ws := h.accounts[gpwallet.Key(addr)]; ws != nil {
+			return ws, nil
+		}
+		return nil, fmt.Errorf("Account %v is not hosted", addr)
+	}
+	if len(h.accounts) == 1 {
+		for _, ws := range h.accounts {
+			return ws, nil
+		}
+	}
+	return nil, errors.New("Request does not name an account")
+}
+
+// byChannel returns the hosted account which participates in the channel
+// with the given ID. If more than one does, the account named in the metadata
+// of ctx decides.
+func (h *Host) byChannel(ctx context.Context, id channel.ID) (*MyWalletService, error) {
+	h.mtx.Lock()
+	var candidates []*MyWalletService
+	for _, ws := range h.accounts {
+		if _, err := ws.ownIndex(id); err == nil {
+			candidates = append(candidates, ws)
+		}
+	}
+	h.mtx.Unlock()
+	switch len(candidates) {
+	case 0:
+		// The channel was restored by the channel service, so only the caller
+		// knows whose channel it is.
+		return h.byHeader(ctx)
+	case 1:
+		return candidates[0], nil
+	}
+	ws, err := h.byHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		if c == ws {
+			return ws, nil
+		}
+	}
+	return nil, fmt.Errorf("Account %v does not participate in channel %x", ws.signer.Address(), id)
+}
+
+// byCKBAddress returns the account of an encoded CKB address.
+func (h *Host) byCKBAddress(s string) (*MyWalletService, error) {
+	addr, err := ckbaddress.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid address %q: %w", s, err)
+	}
+	return h.byLock(addr.Script)
+}
+
+// byLock returns the account with the given lock script.
+func (h *Host) byLock(lock *types.Script) (*MyWalletService, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for _, ws := range h.accounts {
+		if ws.ownLock.Equals(lock) {
+			return ws, nil
+		}
+	}
+	return nil, errors.New("No hosted account has the requested lock script")
+}
+
+// headerAccount returns the account named in the incoming metadata of ctx and
+// whether there is one.
+func headerAccount(ctx context.Context) (gpwallet.Address, bool, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(accountHeader)
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+	b, err := hex.DecodeString(values[0])
+	if err != nil {
+		return nil, false, fmt.Errorf("Invalid account header: %w", err)
+	}
+	var addr address.Participant
+	if err := addr.UnmarshalBinary(b); err != nil {
+		return nil, false, fmt.Errorf("Invalid account header: %w", err)
+	}
+	return &addr, true, nil
+}
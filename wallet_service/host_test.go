@@ -0,0 +1,208 @@
+package wallet_service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/backend"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/signer"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+)
+
+// newTestAccount creates a wallet service for a new account which keeps its
+// data in memory.
+func newTestAccount(t *testing.T) *MyWalletService {
+	key, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	sgn := signer.NewLocalSigner(key, types.NetworkTest)
+	st, err := openStore("")
+	require.NoError(t, err)
+	ownLock := address.AsParticipant(sgn.Address()).PaymentScript
+	chain := &testChain{live: true, txs: make(map[types.Hash]*types.Transaction)}
+	return &MyWalletService{
+		signer:     sgn,
+		network:    types.NetworkTest,
+		states:     make(map[channel.ID]*channel.State),
+		signed:     make(map[channel.ID]*stateSummary),
+		authorized: make(map[channel.ID]*stateSummary),
+		opening:    make(map[channel.ID]*stateSummary),
+		store:      st,
+		chain:      chain,
+		policy:     NewTxPolicy(backend.Deployment{}, ownLock, chain),
+		ownLock:    ownLock,
+		logger:     log.New(io.Discard, "", 0),
+	}
+}
+
+// withAccount returns ctx with the incoming account header of addr.
+func withAccount(t *testing.T, ctx context.Context, addr gpwallet.Address) context.Context {
+	b, err := addr.MarshalBinary()
+	require.NoError(t, err)
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(accountHeader, hex.EncodeToString(b)))
+}
+
+func TestHostRouting(t *testing.T) {
+	ctx := context.Background()
+	alice, bob := newTestAccount(t), newTestAccount(t)
+	h := NewHost()
+	require.NoError(t, h.Add(alice))
+	require.NoError(t, h.Add(bob))
+	require.Error(t, h.Add(alice), "accounts are only hosted once")
+
+	// Signing requests name the account by its address.
+	encoded, err := address.AsParticipant(bob.signer.Address()).ToCKBAddress(types.NetworkTest).Encode()
+	require.NoError(t, err)
+	ws, err := h.byCKBAddress(encoded)
+	require.NoError(t, err)
+	require.Same(t, bob, ws)
+	_, err = h.byLock(newTestAccount(t).ownLock)
+	require.Error(t, err)
+
+	// Other requests are routed by the account header.
+	_, err = h.byHeader(ctx)
+	require.Error(t, err, "no account is named")
+	ws, err = h.byHeader(withAccount(t, ctx, alice.signer.Address()))
+	require.NoError(t, err)
+	require.Same(t, alice, ws)
+
+	// Updates go to the participant of the channel, and the header decides if
+	// both participants are hosted.
+	parts := []gpwallet.Address{alice.signer.Address(), bob.signer.Address()}
+	id := channel.ID{1}
+	require.NoError(t, alice.SetParticipants(id, parts))
+	ws, err = h.byChannel(ctx, id)
+	require.NoError(t, err)
+	require.Same(t, alice, ws)
+	require.NoError(t, bob.SetParticipants(id, parts))
+	_, err = h.byChannel(ctx, id)
+	require.Error(t, err)
+	ws, err = h.byChannel(withAccount(t, ctx, bob.signer.Address()), id)
+	require.NoError(t, err)
+	require.Same(t, bob, ws)
+}
+
+func TestWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "carol.json"), []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "carol.passphrase"), []byte("secret"), 0600))
+	opened := make(chan *MyWalletService, 10)
+	open := func(name, path string) (*MyWalletService, error) {
+		if name == "broken" {
+			return nil, errors.New("wrong passphrase")
+		}
+		ws := newTestAccount(t)
+		opened <- ws
+		return ws, nil
+	}
+	h := NewHost()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.WatchDir(ctx, dir, 10*time.Millisecond, open)
+
+	// Keystores are hosted as wallet-only accounts, which are reached through
+	// the account header like the accounts of participants.
+	carol := <-opened
+	ws, err := h.byHeader(withAccount(t, ctx, carol.signer.Address()))
+	require.NoError(t, err)
+	require.Same(t, carol, ws)
+
+	// Keystores added later on are picked up, and failing ones are skipped.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dave.json"), []byte("{}"), 0600))
+	dave := <-opened
+	ws, err = h.byLock(dave.ownLock)
+	require.NoError(t, err)
+	require.Same(t, dave, ws)
+	select {
+	case <-opened:
+		t.Fatal("keystores are only opened once")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAccountService(t *testing.T) {
+	ws, peer := newTestAccount(t), newTestAccount(t)
+	state := testState(channel.ID{1}, 3, 500)
+	parts := []gpwallet.Address{peer.signer.Address(), ws.signer.Address()}
+	require.NoError(t, ws.SetParticipants(state.ID, parts))
+	ws.setState(state)
+	h := NewHost()
+	require.NoError(t, h.Add(ws))
+
+	s := grpc.NewServer()
+	walletpb.RegisterWalletAccountServer(s, &accountService{h: h})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	c, err := NewAccountClient(conn, ws.signer.Address())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	chs, err := c.Channels(ctx)
+	require.NoError(t, err)
+	require.Len(t, chs, 1)
+	require.NoError(t, state.Equal(chs[0].State))
+	require.Equal(t, parts, chs[0].Participants)
+
+	events := make(chan *walletpb.Event, 10)
+	go c.Subscribe(ctx, func(e *walletpb.Event) { events <- e })
+
+	// Subscriptions start with the open channels.
+	e := <-events
+	require.Equal(t, walletpb.EventType_EVENT_TYPE_UPDATE, e.Type)
+	decoded, err := EventState(e)
+	require.NoError(t, err)
+	require.NoError(t, state.Equal(decoded))
+	eventParts, err := EventParticipants(e)
+	require.NoError(t, err)
+	require.Equal(t, parts, eventParts)
+
+	// The subscription is registered before the open channels are sent.
+	ws.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_SIGNED, Payload: []byte{0}})
+	e = <-events
+	require.Equal(t, walletpb.EventType_EVENT_TYPE_SIGNED, e.Type)
+	require.Equal(t, []byte{0}, e.Payload)
+
+	// Authorizations last until they are revoked.
+	update := testState(state.ID, 4, 400)
+	revoke, err := c.AuthorizeUpdate(ctx, update)
+	require.NoError(t, err)
+	require.Len(t, ws.authorized, 1)
+	revoke()
+	require.Empty(t, ws.authorized)
+
+	// Channels which the client settled are closed once the settlement is
+	// observed.
+	require.NoError(t, c.CloseChannel(ctx, state.ID))
+	settling, err := ws.store.isSettling(state.ID)
+	require.NoError(t, err)
+	require.True(t, settling)
+	require.Equal(t, codes.NotFound, status.Code(c.CloseChannel(ctx, channel.ID{2})))
+	history, err := c.History(ctx, state.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
@@ -0,0 +1,252 @@
+package wallet_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+)
+
+// settlePollInterval is the interval in which the wallet service checks
+// whether a channel which ended off-chain is settled on-chain.
+const settlePollInterval = 5 * time.Second
+
+// errUnknownChannel is returned for requests about a channel which the wallet
+// service does not know.
+var errUnknownChannel = errors.New("unknown channel")
+
+// settlement is the on-chain settlement of a channel.
+type settlement struct {
+	tx    types.Hash
+	ckb   *big.Int                // Net CKBytes which we received in Shannon.
+	sudts map[types.Hash]*big.Int // Net SUDT amounts which we received.
+}
+
+// payout returns the amount of every asset which we received as decimal
+// number.
+func (s *settlement) payout(assets []channel.Asset) []string {
+	amounts := make([]string, len(assets))
+	for i, a := range assets {
+		ckbAsset, ok := a.(*asset.Asset)
+		switch {
+		case !ok:
+			amounts[i] = "0"
+		case ckbAsset.IsCKBytes:
+			amounts[i] = s.ckb.String()
+		default:
+			amounts[i] = amountOf(s.sudts, ckbAsset.SUDT.TypeScript.Hash()).String()
+		}
+	}
+	return amounts
+}
+
+// recordChannelCell remembers the type script of the channel cell which the
+// transaction creates, so that the settlement of the channel can be observed.
+func (wsc *MyWalletService) recordChannelCell(tx *types.Transaction) {
+	for i, out := range tx.Outputs {
+		if !wsc.policy.isPCTS(out.Type) || i >= len(tx.OutputsData) {
+			continue
+		}
+		status, err := molecule.ChannelStatusFromSlice(tx.OutputsData[i], false)
+		if err != nil {
+			wsc.logger.Printf("Error decoding channel status of output %d: %v", i, err)
+			continue
+		}
+		id := channel.ID(types.UnpackHash(status.State().ChannelId()))
+		if err := wsc.store.putPCTS(id, out.Type); err != nil {
+			wsc.logger.Printf("Error storing type script of channel %x: %v", id, err)
+		}
+	}
+}
+
+// CloseChannel notifies the wallet service that the client settled the
+// channel with the given ID, e.g., by a force close. The channel is closed
+// once the settlement is observed on-chain. Closing an already closed channel
+// does nothing.
+func (wsc *MyWalletService) CloseChannel(id channel.ID) error {
+	if wsc.getState(id) == nil {
+		if closed, err := wsc.store.isClosed(id); err != nil || closed {
+			return err
+		}
+		return fmt.Errorf("%w %x", errUnknownChannel, id)
+	}
+	if err := wsc.store.putSettling(id); err != nil {
+		return fmt.Errorf("marking channel %x as settling: %w", id, err)
+	}
+	wsc.awaitSettlement(id)
+	return nil
+}
+
+// awaitSettlement watches the chain until the channel with the given ID is
+// settled and closes it then. Every channel is watched only once.
+func (wsc *MyWalletService) awaitSettlement(id channel.ID) {
+	wsc.settleMtx.Lock()
+	defer wsc.settleMtx.Unlock()
+	if wsc.watching == nil {
+		wsc.watching = make(map[channel.ID]struct{})
+	}
+	if _, ok := wsc.watching[id]; ok {
+		return
+	}
+	wsc.watching[id] = struct{}{}
+	go wsc.watchSettlement(id)
+}
+
+func (wsc *MyWalletService) watchSettlement(id channel.ID) {
+	defer func() {
+		wsc.settleMtx.Lock()
+		defer wsc.settleMtx.Unlock()
+		delete(wsc.watching, id)
+	}()
+	ticker := time.NewTicker(settlePollInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), settlePollInterval)
+		s, err := wsc.findSettlement(ctx, id)
+		cancel()
+		if err != nil {
+			wsc.logger.Printf("Error looking up the settlement of channel %x: %v", id, err)
+		} else if s != nil {
+			if err := wsc.closeChannel(id, s); err != nil {
+				wsc.logger.Println("Error closing channel:", err)
+			}
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-wsc.done:
+			return
+		}
+	}
+}
+
+// findSettlement returns the settlement of the channel with the given ID, or
+// nil if its channel cell was not consumed yet.
+func (wsc *MyWalletService) findSettlement(ctx context.Context, id channel.ID) (*settlement, error) {
+	pcts, err := wsc.channelCell(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key := &indexer.SearchKey{
+		Script:           pcts,
+		ScriptType:       types.ScriptTypeType,
+		ScriptSearchMode: types.ScriptSearchModeExact,
+	}
+	live, err := wsc.chain.GetCells(ctx, key, indexer.SearchOrderDesc, 1, "")
+	if err != nil {
+		return nil, fmt.Errorf("looking up channel cell: %w", err)
+	}
+	if len(live.Objects) > 0 {
+		return nil, nil
+	}
+	// The latest transaction with the channel cell consumed it.
+	txs, err := wsc.chain.GetTransactions(ctx, key, indexer.SearchOrderDesc, 1, "")
+	if err != nil {
+		return nil, fmt.Errorf("looking up channel transactions: %w", err)
+	}
+	if len(txs.Objects) == 0 {
+		return nil, nil
+	}
+	hash := txs.Objects[0].TxHash
+	res, err := wsc.chain.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("looking up transaction %s: %w", hash, err)
+	}
+	if res == nil || res.Transaction == nil {
+		return nil, fmt.Errorf("unknown transaction %s", hash)
+	}
+	inputs, err := resolveInputs(ctx, wsc.chain, res.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	s := &settlement{tx: hash, ckb: new(big.Int), sudts: make(map[types.Hash]*big.Int)}
+	add := func(c cell, sign int) {
+		if c.output.Lock == nil || !c.output.Lock.Equals(wsc.ownLock) {
+			return
+		}
+		s.ckb.Add(s.ckb, new(big.Int).Mul(big.NewInt(int64(sign)), new(big.Int).SetUint64(c.output.Capacity)))
+		if c.output.Type != nil {
+			h := c.output.Type.Hash()
+			amount := new(big.Int).Mul(big.NewInt(int64(sign)), sudtAmount(c.data))
+			s.sudts[h] = amount.Add(amount, amountOf(s.sudts, h))
+		}
+	}
+	for _, in := range inputs {
+		add(in, -1)
+	}
+	for i, out := range res.Transaction.Outputs {
+		if i < len(res.Transaction.OutputsData) {
+			add(cell{output: out, data: res.Transaction.OutputsData[i]}, 1)
+		}
+	}
+	return s, nil
+}
+
+// channelCell returns the type script of the cell of the channel with the
+// given ID. If it was not recorded when we signed the channel's funding, the
+// live channel cells are searched for it.
+func (wsc *MyWalletService) channelCell(ctx context.Context, id channel.ID) (*types.Script, error) {
+	if pcts, err := wsc.store.pcts(id); err != nil || pcts != nil {
+		return pcts, err
+	}
+	d := wsc.policy.deployment
+	key := &indexer.SearchKey{
+		Script:           &types.Script{CodeHash: d.PCTSCodeHash, HashType: d.PCTSHashType, Args: []byte{}},
+		ScriptType:       types.ScriptTypeType,
+		ScriptSearchMode: types.ScriptSearchModePrefix,
+		WithData:         true,
+	}
+	cells, err := wsc.chain.GetCells(ctx, key, indexer.SearchOrderDesc, math.MaxUint32, "")
+	if err != nil {
+		return nil, fmt.Errorf("looking up channel cells: %w", err)
+	}
+	for _, c := range cells.Objects {
+		status, err := molecule.ChannelStatusFromSlice(c.OutputData, false)
+		if err != nil || channel.ID(types.UnpackHash(status.State().ChannelId())) != id {
+			continue
+		}
+		if err := wsc.store.putPCTS(id, c.Output.Type); err != nil {
+			wsc.logger.Printf("Error storing type script of channel %x: %v", id, err)
+		}
+		return c.Output.Type, nil
+	}
+	return nil, fmt.Errorf("cell of channel %x is unknown", id)
+}
+
+// closeChannel closes the channel with the given ID after its settlement s.
+// The latest state of the channel is forgotten and a closed event with its
+// final state and our payout is published. Its history is kept.
+func (wsc *MyWalletService) closeChannel(id channel.ID, s *settlement) error {
+	wsc.stateMtx.Lock()
+	state, ok := wsc.states[id]
+	if !ok {
+		wsc.stateMtx.Unlock()
+		return nil
+	}
+	delete(wsc.states, id)
+	err := wsc.store.closeChannel(id)
+	wsc.stateMtx.Unlock()
+	if err != nil {
+		return fmt.Errorf("removing state of channel %x: %w", id, err)
+	}
+
+	wsc.logger.Printf("Channel %x closed at version %d by transaction %s", id, state.Version, s.tx)
+	e, err := wsc.updateEvent(nil, state)
+	if err != nil {
+		return fmt.Errorf("creating close event: %w", err)
+	}
+	e.Type = walletpb.EventType_EVENT_TYPE_CLOSED
+	e.SettleTx = s.tx.Bytes()
+	e.Payout = s.payout(state.Allocation.Assets)
+	wsc.publish(e)
+	return nil
+}
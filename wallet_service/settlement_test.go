@@ -0,0 +1,131 @@
+package wallet_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
+	"polycry.pt/poly-go/sync"
+)
+
+// testChain is a chain with a single channel cell, which is live until it is
+// consumed by the transaction settle.
+type testChain struct {
+	mtx    sync.Mutex
+	live   bool
+	settle types.Hash
+	txs    map[types.Hash]*types.Transaction
+}
+
+func (c *testChain) GetTransaction(_ context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	tx, ok := c.txs[hash]
+	if !ok {
+		return nil, nil
+	}
+	return &types.TransactionWithStatus{Transaction: tx}, nil
+}
+
+func (c *testChain) GetCells(context.Context, *indexer.SearchKey, indexer.SearchOrder, uint64, string) (*indexer.LiveCells, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	cells := new(indexer.LiveCells)
+	if c.live {
+		cells.Objects = append(cells.Objects, &indexer.LiveCell{})
+	}
+	return cells, nil
+}
+
+func (c *testChain) GetTransactions(context.Context, *indexer.SearchKey, indexer.SearchOrder, uint64, string) (*indexer.TxsWithCell, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	txs := new(indexer.TxsWithCell)
+	if !c.live {
+		txs.Objects = append(txs.Objects, &indexer.TxWithCell{TxHash: c.settle, IoType: indexer.IOTypeIn})
+	}
+	return txs, nil
+}
+
+// settleChannel consumes the channel cell by a transaction which pays out
+// amount to lock and takes fee from an input of lock.
+func (c *testChain) settleChannel(lock *types.Script, amount, fee uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	funds := &types.Transaction{
+		Outputs:     []*types.CellOutput{{Capacity: 1000, Lock: lock}},
+		OutputsData: [][]byte{{}},
+	}
+	c.txs[funds.ComputeHash()] = funds
+	settle := &types.Transaction{
+		Inputs:      []*types.CellInput{{PreviousOutput: &types.OutPoint{TxHash: funds.ComputeHash()}}},
+		Outputs:     []*types.CellOutput{{Capacity: amount, Lock: lock}, {Capacity: 1000 - fee, Lock: lock}},
+		OutputsData: [][]byte{{}, {}},
+	}
+	c.settle = settle.ComputeHash()
+	c.txs[c.settle] = settle
+	c.live = false
+}
+
+func TestSettlement(t *testing.T) {
+	ws, peer := newTestAccount(t), newTestAccount(t)
+	ws.done = make(chan struct{})
+	close(ws.done) // Watch only once.
+	chain := ws.chain.(*testChain)
+	events, _, cancel := ws.subscribe()
+	defer cancel()
+	notify := func(state *channel.State) bool {
+		protoState, err := protobuf.FromState(state)
+		require.NoError(t, err)
+		resp, err := ws.UpdateNotification(context.Background(), &proto.UpdateNotificationRequest{State: protoState})
+		require.NoError(t, err)
+		return resp.Accepted
+	}
+	state := testState(channel.ID{1}, 1, 500)
+	require.NoError(t, ws.SetParticipants(state.ID, []gpwallet.Address{peer.signer.Address(), ws.signer.Address()}))
+	require.NoError(t, ws.store.putPCTS(state.ID, &types.Script{HashType: types.HashTypeData, Args: []byte{1}}))
+	ws.setState(state)
+
+	// Both participants agreed on the final state once we signed it, but the
+	// channel stays open until it is settled.
+	final := testState(state.ID, 2, 400)
+	final.IsFinal = true
+	summary, err := summarize(final)
+	require.NoError(t, err)
+	ws.signed[final.ID] = summary
+	require.True(t, notify(final))
+	require.Len(t, ws.Channels(), 1)
+	s, err := ws.findSettlement(context.Background(), state.ID)
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	// Until then, only the final state is accepted.
+	require.True(t, notify(final))
+	require.False(t, notify(testState(state.ID, 3, 300)))
+
+	// The settlement closes the channel and reports our payout without the fee.
+	chain.settleChannel(ws.ownLock, 600, 5)
+	ws.watchSettlement(state.ID)
+	require.Empty(t, ws.Channels())
+	var closed *walletpb.Event
+	for closed == nil {
+		if e := <-events; e.Type == walletpb.EventType_EVENT_TYPE_CLOSED {
+			closed = e
+		}
+	}
+	require.Equal(t, chain.settle.Bytes(), closed.SettleTx)
+	require.Equal(t, []string{"595"}, closed.Payout)
+
+	// Repeated notifications of the final state are still accepted.
+	require.True(t, notify(final))
+	require.False(t, notify(testState(state.ID, 3, 300)))
+	require.NoError(t, ws.CloseChannel(state.ID))
+	require.ErrorIs(t, ws.CloseChannel(channel.ID{2}), errUnknownChannel)
+}
@@ -0,0 +1,122 @@
+package wallet_service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/encoding"
+	molecule2 "perun.network/perun-ckb-backend/encoding/molecule"
+)
+
+// ckbytesKey is the key of CKBytes in stateSummary.balances. SUDTs are keyed
+// by the hash of their type script.
+const ckbytesKey = "CKBytes"
+
+// stateSummary is the part of a channel state which is signed by the
+// participants, i.e., the on-chain encoding of the state.
+type stateSummary struct {
+	id       channel.ID
+	version  uint64
+	isFinal  bool
+	balances map[string][2]*big.Int
+}
+
+// decodeState decodes the data of a signing request, which is the on-chain
+// encoding of a channel state.
+func decodeState(data []byte) (*stateSummary, error) {
+	packed, err := molecule.ChannelStateFromSlice(data, false)
+	if err != nil {
+		return nil, fmt.Errorf("Data is not a channel state: %w", err)
+	}
+	id, err := molecule2.UnpackByte32(packed.ChannelId())
+	if err != nil {
+		return nil, fmt.Errorf("Invalid channel ID: %w", err)
+	}
+	s := &stateSummary{
+		id:       id,
+		version:  molecule2.UnpackUint64(packed.Version()),
+		isFinal:  encoding.ToBool(*packed.IsFinal()),
+		balances: make(map[string][2]*big.Int),
+	}
+	bals := packed.Balances()
+	ckb := bals.Ckbytes()
+	s.balances[ckbytesKey] = [2]*big.Int{
+		new(big.Int).SetUint64(molecule2.UnpackUint64(ckb.Nth0())),
+		new(big.Int).SetUint64(molecule2.UnpackUint64(ckb.Nth1())),
+	}
+	sudts := bals.Sudts()
+	for i := uint(0); i < sudts.Len(); i++ {
+		sudt := sudts.Get(i)
+		key := sudt.Asset().TypeScript().AsSlice()
+		d := sudt.Distribution()
+		s.balances[fmt.Sprintf("%x", key)] = [2]*big.Int{
+			molecule2.UnpackUint128(d.Nth0()).Big(),
+			molecule2.UnpackUint128(d.Nth1()).Big(),
+		}
+	}
+	return s, nil
+}
+
+// summarize returns the summary of the given state.
+func summarize(state *channel.State) (*stateSummary, error) {
+	packed, err := encoding.PackChannelState(state)
+	if err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+	return decodeState(packed.AsSlice())
+}
+
+// equal returns whether both summaries describe the same state.
+func (s *stateSummary) equal(o *stateSummary) bool {
+	return s.id == o.id && s.version == o.version && s.isFinal == o.isFinal && s.sameBalances(o)
+}
+
+// sameBalances returns whether both summaries have the same balances.
+func (s *stateSummary) sameBalances(o *stateSummary) bool {
+	if len(s.balances) != len(o.balances) {
+		return false
+	}
+	for key, bals := range s.balances {
+		obals, ok := o.balances[key]
+		if !ok || bals[0].Cmp(obals[0]) != 0 || bals[1].Cmp(obals[1]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyTransition checks that the channel may go from state from to state to.
+// The version has to increase, the total of every asset has to stay the same
+// and the balance of participant idx may only drop if the update is ours.
+func verifyTransition(from, to *stateSummary, idx int, ours bool) error {
+	if from.id != to.id {
+		return errors.New("Channel ID mismatch")
+	}
+	if from.isFinal {
+		return errors.New("Channel is already final")
+	}
+	if to.version <= from.version {
+		return fmt.Errorf("Version %d does not increase the current version %d", to.version, from.version)
+	}
+	if len(from.balances) != len(to.balances) {
+		return errors.New("Update changes the assets of the channel")
+	}
+	for key, fromBals := range from.balances {
+		toBals, ok := to.balances[key]
+		if !ok {
+			return errors.New("Update changes the assets of the channel")
+		}
+		fromTotal := new(big.Int).Add(fromBals[0], fromBals[1])
+		toTotal := new(big.Int).Add(toBals[0], toBals[1])
+		if fromTotal.Cmp(toTotal) != 0 {
+			return fmt.Errorf("Update changes the total of asset %s from %v to %v", key, fromTotal, toTotal)
+		}
+		if !ours && toBals[idx].Cmp(fromBals[idx]) < 0 {
+			return fmt.Errorf("Update by the peer decreases our balance of asset %s from %v to %v", key, fromBals[idx], toBals[idx])
+		}
+	}
+	return nil
+}
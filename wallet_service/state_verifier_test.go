@@ -0,0 +1,43 @@
+package wallet_service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	"perun.network/perun-ckb-backend/channel/asset"
+)
+
+func TestVerifyTransition(t *testing.T) {
+	from := testSummary(t, 1, 500, 400)
+	require.NoError(t, verifyTransition(from, testSummary(t, 2, 600, 300), 1, true))
+	require.NoError(t, verifyTransition(from, testSummary(t, 2, 400, 500), 1, false))
+
+	// Only we may decrease our balance.
+	require.ErrorContains(t, verifyTransition(from, testSummary(t, 2, 600, 300), 1, false), "decreases our balance")
+	// The version has to increase.
+	require.ErrorContains(t, verifyTransition(from, testSummary(t, 1, 400, 500), 1, false), "does not increase")
+	// The total of each asset is fixed.
+	require.ErrorContains(t, verifyTransition(from, testSummary(t, 2, 500, 500), 1, false), "changes the total")
+
+	final := testSummary(t, 2, 500, 400)
+	final.isFinal = true
+	require.NoError(t, verifyTransition(from, final, 0, false))
+	require.ErrorContains(t, verifyTransition(final, testSummary(t, 3, 500, 400), 0, false), "already final")
+}
+
+func testSummary(t *testing.T, version uint64, bals ...int64) *stateSummary {
+	ckb := asset.NewCKBytesAsset()
+	alloc := channel.NewAllocation(2, ckb)
+	alloc.SetAssetBalances(ckb, []channel.Bal{big.NewInt(bals[0]), big.NewInt(bals[1])})
+	s, err := summarize(&channel.State{
+		ID:         channel.ID{1},
+		Version:    version,
+		Allocation: *alloc,
+		App:        channel.NoApp(),
+		Data:       channel.NoData(),
+	})
+	require.NoError(t, err)
+	return s
+}
@@ -0,0 +1,182 @@
+package wallet_service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"polycry.pt/poly-go/sortedkv"
+	"polycry.pt/poly-go/sortedkv/leveldb"
+	"polycry.pt/poly-go/sortedkv/memorydb"
+)
+
+const (
+	// partsPrefix is the key prefix of the channel participants.
+	partsPrefix = "parts:"
+	// statePrefix is the key prefix of the latest state of the open channels.
+	statePrefix = "state:"
+	// historyPrefix is the key prefix of all states of the channels, which are
+	// ordered by version.
+	historyPrefix = "history:"
+	// closedPrefix is the key prefix of the markers of closed channels.
+	closedPrefix = "closed:"
+	// settlingPrefix is the key prefix of the markers of channels whose
+	// settlement is awaited.
+	settlingPrefix = "settling:"
+	// pctsPrefix is the key prefix of the type scripts of the channel cells.
+	pctsPrefix = "pcts:"
+)
+
+// store persists the data of the wallet service which is needed to resume
+// after a restart.
+type store struct {
+	db sortedkv.Database
+}
+
+// openStore opens the store in the LevelDB directory dir. If dir is empty, the
+// data is only kept in memory.
+func openStore(dir string) (*store, error) {
+	if dir == "" {
+		return &store{db: memorydb.NewDatabase()}, nil
+	}
+	db, err := leveldb.LoadDatabase(dir)
+	if err != nil {
+		return nil, fmt.Errorf("loading database: %w", err)
+	}
+	return &store{db: db}, nil
+}
+
+// participants returns the participants of the channel with the given ID or
+// nil if they are unknown.
+func (s *store) participants(id channel.ID) ([]gpwallet.Address, error) {
+	key := partsPrefix + string(id[:])
+	// The backends report missing keys differently, so we check first.
+	if ok, err := s.db.Has(key); err != nil || !ok {
+		return nil, err
+	}
+	b, err := s.db.GetBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	var parts gpwallet.AddressesWithLen
+	if err := parts.Decode(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("decoding participants: %w", err)
+	}
+	return parts, nil
+}
+
+func (s *store) putParticipants(id channel.ID, parts []gpwallet.Address) error {
+	var buf bytes.Buffer
+	if err := gpwallet.AddressesWithLen(parts).Encode(&buf); err != nil {
+		return fmt.Errorf("encoding participants: %w", err)
+	}
+	return s.db.PutBytes(partsPrefix+string(id[:]), buf.Bytes())
+}
+
+// states returns the latest states of all open channels.
+func (s *store) states() ([]*channel.State, error) {
+	return s.statesWithPrefix(statePrefix)
+}
+
+// history returns all states of the channel with the given ID ordered by
+// version.
+func (s *store) history(id channel.ID) ([]*channel.State, error) {
+	return s.statesWithPrefix(historyPrefix + string(id[:]))
+}
+
+func (s *store) statesWithPrefix(prefix string) ([]*channel.State, error) {
+	it := s.db.NewIteratorWithPrefix(prefix)
+	defer it.Close()
+	var states []*channel.State
+	for it.Next() {
+		state := new(channel.State)
+		if err := state.Decode(bytes.NewReader(it.ValueBytes())); err != nil {
+			return nil, fmt.Errorf("decoding state: %w", err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// putState stores state as the latest state of its channel and adds it to
+// the channel's history.
+func (s *store) putState(state *channel.State) error {
+	var buf bytes.Buffer
+	if err := state.Encode(&buf); err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+	var version [8]byte
+	binary.BigEndian.PutUint64(version[:], state.Version)
+	b := s.db.NewBatch()
+	if err := b.PutBytes(statePrefix+string(state.ID[:]), buf.Bytes()); err != nil {
+		return err
+	}
+	if err := b.PutBytes(historyPrefix+string(state.ID[:])+string(version[:]), buf.Bytes()); err != nil {
+		return err
+	}
+	return b.Apply()
+}
+
+// closeChannel removes the latest state of the channel with the given ID and
+// marks the channel as closed. Its history is kept.
+func (s *store) closeChannel(id channel.ID) error {
+	b := s.db.NewBatch()
+	for _, key := range []string{statePrefix + string(id[:]), settlingPrefix + string(id[:])} {
+		if ok, err := s.db.Has(key); err != nil {
+			return err
+		} else if ok {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	if err := b.Put(closedPrefix+string(id[:]), ""); err != nil {
+		return err
+	}
+	return b.Apply()
+}
+
+// putSettling marks the channel with the given ID as settling.
+func (s *store) putSettling(id channel.ID) error {
+	return s.db.Put(settlingPrefix+string(id[:]), "")
+}
+
+// isSettling returns whether the channel with the given ID is settling.
+func (s *store) isSettling(id channel.ID) (bool, error) {
+	return s.db.Has(settlingPrefix + string(id[:]))
+}
+
+// pcts returns the type script of the cell of the channel with the given ID
+// or nil if it is unknown.
+func (s *store) pcts(id channel.ID) (*types.Script, error) {
+	key := pctsPrefix + string(id[:])
+	if ok, err := s.db.Has(key); err != nil || !ok {
+		return nil, err
+	}
+	b, err := s.db.GetBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	script, err := molecule.ScriptFromSlice(b, false)
+	if err != nil {
+		return nil, fmt.Errorf("decoding channel type script: %w", err)
+	}
+	return types.UnpackScript(script), nil
+}
+
+func (s *store) putPCTS(id channel.ID, pcts *types.Script) error {
+	return s.db.PutBytes(pctsPrefix+string(id[:]), pcts.Pack().AsSlice())
+}
+
+// isClosed returns whether the channel with the given ID was closed.
+func (s *store) isClosed(id channel.ID) (bool, error) {
+	return s.db.Has(closedPrefix + string(id[:]))
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
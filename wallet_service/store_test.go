@@ -0,0 +1,84 @@
+package wallet_service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/channel/asset"
+)
+
+func TestStoreStates(t *testing.T) {
+	dir := t.TempDir()
+	st, err := openStore(dir)
+	require.NoError(t, err)
+	a, b := testState(channel.ID{1}, 0, 500), testState(channel.ID{2}, 0, 100)
+	require.NoError(t, st.putState(a))
+	require.NoError(t, st.putState(b))
+	a2 := testState(a.ID, 1, 400)
+	require.NoError(t, st.putState(a2))
+	require.NoError(t, st.closeChannel(b.ID))
+	require.NoError(t, st.close())
+
+	// The latest states of the open channels and all histories are reloaded.
+	st, err = openStore(dir)
+	require.NoError(t, err)
+	defer st.close()
+	states, err := st.states()
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	require.NoError(t, a2.Equal(states[0]))
+	history, err := st.history(a.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.NoError(t, a.Equal(history[0]))
+	require.NoError(t, a2.Equal(history[1]))
+	history, err = st.history(b.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	closed, err := st.isClosed(b.ID)
+	require.NoError(t, err)
+	require.True(t, closed)
+}
+
+func TestStoreParticipants(t *testing.T) {
+	dir := t.TempDir()
+	st, err := openStore(dir)
+	require.NoError(t, err)
+	parts := []gpwallet.Address{
+		newTestAccount(t).signer.Address(),
+		newTestAccount(t).signer.Address(),
+		newTestAccount(t).signer.Address(),
+	}
+	require.NoError(t, st.putParticipants(channel.ID{1}, parts))
+	require.NoError(t, st.close())
+
+	// The participants are reloaded in channel order.
+	st, err = openStore(dir)
+	require.NoError(t, err)
+	defer st.close()
+	loaded, err := st.participants(channel.ID{1})
+	require.NoError(t, err)
+	require.Len(t, loaded, len(parts))
+	for i := range parts {
+		require.True(t, parts[i].Equal(loaded[i]))
+	}
+	unknown, err := st.participants(channel.ID{2})
+	require.NoError(t, err)
+	require.Nil(t, unknown)
+}
+
+func testState(id channel.ID, version uint64, bal int64) *channel.State {
+	ckb := asset.NewCKBytesAsset()
+	alloc := channel.NewAllocation(2, ckb)
+	alloc.SetAssetBalances(ckb, []channel.Bal{big.NewInt(bal), big.NewInt(1000 - bal)})
+	return &channel.State{
+		ID:         id,
+		Version:    version,
+		Allocation: *alloc,
+		App:        channel.NoApp(),
+		Data:       channel.NoData(),
+	}
+}
@@ -0,0 +1,395 @@
+package wallet_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"perun.network/perun-ckb-backend/backend"
+	molecule2 "perun.network/perun-ckb-backend/encoding/molecule"
+)
+
+// MaxTransactionFee is the highest fee in Shannon which the wallet service
+// pays for a single transaction (0.1 CKByte).
+const MaxTransactionFee uint64 = 10_000_000
+
+// ChainReader looks up the transactions which created the inputs of a
+// transaction. It is implemented by rpc.Client.
+type ChainReader interface {
+	GetTransaction(ctx context.Context, hash types.Hash) (*types.TransactionWithStatus, error)
+}
+
+// TxPolicy decides whether the wallet service signs a transaction. It only
+// accepts transactions of which every input and output is either one of our
+// own cells or a cell of a Perun channel in which we participate, and which
+// spend no more of our funds than our contribution to the channel plus a fee.
+type TxPolicy struct {
+	deployment backend.Deployment
+	ownLock    *types.Script
+	chain      ChainReader
+}
+
+// NewTxPolicy creates the signing policy for the account with the lock script
+// ownLock. The inputs of the transactions are looked up at chain.
+func NewTxPolicy(deployment backend.Deployment, ownLock *types.Script, chain ChainReader) *TxPolicy {
+	return &TxPolicy{deployment: deployment, ownLock: ownLock, chain: chain}
+}
+
+// cellKind classifies the cells of a transaction.
+type cellKind int
+
+const (
+	ownCell     cellKind = iota // Locked by our own lock script.
+	channelCell                 // The channel cell, typed by the PCTS.
+	fundsCell                   // Channel funds, locked by the PFLS.
+	payoutCell                  // Payout to another channel participant.
+)
+
+// cell is an input or output of a transaction.
+type cell struct {
+	output *types.CellOutput
+	data   []byte
+}
+
+// txChannel is the channel which a transaction interacts with.
+type txChannel struct {
+	pcts      *types.Script
+	fundsLock *types.Script
+	parts     [2]types.Hash // Payment script hashes of the participants.
+	idx       int           // Our index in the channel.
+}
+
+// Check returns an error describing why the transaction must not be signed,
+// or nil if it complies with the policy.
+func (p *TxPolicy) Check(ctx context.Context, tx *types.Transaction) error {
+	if tx == nil {
+		return errors.New("missing transaction")
+	}
+	inputs, err := resolveInputs(ctx, p.chain, tx)
+	if err != nil {
+		return err
+	}
+	if len(tx.OutputsData) != len(tx.Outputs) {
+		return fmt.Errorf("transaction has %d outputs but %d output data", len(tx.Outputs), len(tx.OutputsData))
+	}
+	outputs := make([]cell, len(tx.Outputs))
+	for i, out := range tx.Outputs {
+		outputs[i] = cell{output: out, data: tx.OutputsData[i]}
+	}
+	ch, err := p.channel(inputs, outputs)
+	if err != nil {
+		return err
+	}
+
+	b := newTxBalance()
+	var channelInput, channelOutput *cell
+	for i, in := range inputs {
+		kind, err := p.classify(in, ch, false)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		if kind == channelCell {
+			channelInput = &inputs[i]
+		}
+		b.add(kind, in, true)
+	}
+	for i, out := range outputs {
+		kind, err := p.classify(out, ch, true)
+		if err != nil {
+			return fmt.Errorf("output %d: %w", i, err)
+		}
+		if kind == channelCell {
+			channelOutput = &outputs[i]
+		}
+		b.add(kind, out, false)
+	}
+
+	if b.inCounts[channelCell] > 1 || b.counts[channelCell] > 1 {
+		return errors.New("transaction involves more than one channel cell")
+	}
+	if b.counts[payoutCell] > 0 && (channelInput == nil || channelOutput != nil) {
+		return errors.New("transaction pays out channel participants without closing the channel")
+	}
+	if err := checkChannelCell(channelInput, channelOutput); err != nil {
+		return err
+	}
+	if err := b.checkOutflow(); err != nil {
+		return err
+	}
+	if b.counts[fundsCell] > 0 {
+		if b.inCounts[fundsCell] > 0 {
+			return errors.New("transaction both locks and releases channel funds")
+		}
+		if channelOutput == nil {
+			return errors.New("transaction funds a channel without a channel cell")
+		}
+		return checkFunding(b, channelOutput, ch.idx)
+	}
+	return nil
+}
+
+// resolveInputs looks up the cells which are consumed by the transaction.
+func resolveInputs(ctx context.Context, chain ChainReader, tx *types.Transaction) ([]cell, error) {
+	txs := make(map[types.Hash]*types.Transaction)
+	inputs := make([]cell, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		if in == nil || in.PreviousOutput == nil {
+			return nil, fmt.Errorf("input %d: missing previous output", i)
+		}
+		prev := in.PreviousOutput
+		prevTx, ok := txs[prev.TxHash]
+		if !ok {
+			res, err := chain.GetTransaction(ctx, prev.TxHash)
+			if err != nil {
+				return nil, fmt.Errorf("input %d: looking up transaction %s: %w", i, prev.TxHash, err)
+			}
+			if res == nil || res.Transaction == nil {
+				return nil, fmt.Errorf("input %d: unknown transaction %s", i, prev.TxHash)
+			}
+			prevTx = res.Transaction
+			txs[prev.TxHash] = prevTx
+		}
+		if int(prev.Index) >= len(prevTx.Outputs) || int(prev.Index) >= len(prevTx.OutputsData) {
+			return nil, fmt.Errorf("input %d: transaction %s has no output %d", i, prev.TxHash, prev.Index)
+		}
+		inputs[i] = cell{output: prevTx.Outputs[prev.Index], data: prevTx.OutputsData[prev.Index]}
+	}
+	return inputs, nil
+}
+
+// channel returns the channel which the transaction interacts with, or nil if
+// it does not contain a channel cell. A transaction may only interact with a
+// single channel and we have to be a participant of it.
+func (p *TxPolicy) channel(inputs, outputs []cell) (*txChannel, error) {
+	var pcts *types.Script
+	for _, c := range append(append([]cell{}, inputs...), outputs...) {
+		if !p.isPCTS(c.output.Type) {
+			continue
+		}
+		if pcts == nil {
+			pcts = c.output.Type
+		} else if !pcts.Equals(c.output.Type) {
+			return nil, errors.New("transaction involves more than one channel")
+		}
+	}
+	if pcts == nil {
+		return nil, nil
+	}
+	constants, err := molecule.ChannelConstantsFromSlice(pcts.Args, false)
+	if err != nil {
+		return nil, fmt.Errorf("decoding channel constants: %w", err)
+	}
+	ch := &txChannel{
+		pcts: pcts,
+		fundsLock: &types.Script{
+			CodeHash: p.deployment.PFLSCodeHash,
+			HashType: p.deployment.PFLSHashType,
+			Args:     pcts.Hash().Bytes(),
+		},
+		idx: -1,
+	}
+	for i, part := range []*molecule.Participant{constants.Params().PartyA(), constants.Params().PartyB()} {
+		ch.parts[i] = types.BytesToHash(part.PaymentScriptHash().AsSlice())
+		if ch.parts[i] == p.ownLock.Hash() {
+			ch.idx = i
+		}
+	}
+	if ch.idx < 0 {
+		return nil, errors.New("transaction involves a channel in which we do not participate")
+	}
+	return ch, nil
+}
+
+// classify returns the kind of the cell or an error if the cell is not
+// expected in a transaction with the channel ch.
+func (p *TxPolicy) classify(c cell, ch *txChannel, isOutput bool) (cellKind, error) {
+	lock, typ := c.output.Lock, c.output.Type
+	if lock == nil {
+		return 0, errors.New("missing lock script")
+	}
+	if p.isPCTS(typ) {
+		if !p.isPCLS(lock) {
+			return 0, fmt.Errorf("channel cell has unexpected lock script %s", lock.Hash())
+		}
+		return channelCell, nil
+	}
+	if typ != nil && !p.isSUDT(typ) {
+		return 0, fmt.Errorf("unexpected type script %s", typ.Hash())
+	}
+	switch {
+	case lock.Equals(p.ownLock):
+		return ownCell, nil
+	case ch != nil && lock.Equals(ch.fundsLock):
+		return fundsCell, nil
+	case ch != nil && isOutput && lock.Hash() == ch.parts[1-ch.idx]:
+		return payoutCell, nil
+	}
+	return 0, fmt.Errorf("unexpected lock script %s", lock.Hash())
+}
+
+func (p *TxPolicy) isPCTS(s *types.Script) bool {
+	return s != nil && s.CodeHash == p.deployment.PCTSCodeHash && s.HashType == p.deployment.PCTSHashType
+}
+
+func (p *TxPolicy) isPCLS(s *types.Script) bool {
+	return s != nil && s.CodeHash == p.deployment.PCLSCodeHash && s.HashType == p.deployment.PCLSHashType
+}
+
+func (p *TxPolicy) isSUDT(s *types.Script) bool {
+	for _, sudt := range p.deployment.SUDTs {
+		if s.Equals(&sudt) {
+			return true
+		}
+	}
+	return false
+}
+
+// txBalance sums up the CKBytes and SUDTs of the cells of a transaction by
+// their kind.
+type txBalance struct {
+	counts, inCounts map[cellKind]int
+	ckbIn, ckbOut    map[cellKind]uint64
+	// SUDT amounts by kind and type script hash.
+	sudtIn, sudtOut map[cellKind]map[types.Hash]*big.Int
+}
+
+func newTxBalance() *txBalance {
+	return &txBalance{
+		counts:   make(map[cellKind]int),
+		inCounts: make(map[cellKind]int),
+		ckbIn:    make(map[cellKind]uint64),
+		ckbOut:   make(map[cellKind]uint64),
+		sudtIn:   make(map[cellKind]map[types.Hash]*big.Int),
+		sudtOut:  make(map[cellKind]map[types.Hash]*big.Int),
+	}
+}
+
+func (b *txBalance) add(kind cellKind, c cell, isInput bool) {
+	ckb, sudt, counts := b.ckbOut, b.sudtOut, b.counts
+	if isInput {
+		ckb, sudt, counts = b.ckbIn, b.sudtIn, b.inCounts
+	}
+	counts[kind]++
+	ckb[kind] += c.output.Capacity
+	if c.output.Type == nil || kind == channelCell {
+		return
+	}
+	if sudt[kind] == nil {
+		sudt[kind] = make(map[types.Hash]*big.Int)
+	}
+	h := c.output.Type.Hash()
+	if sudt[kind][h] == nil {
+		sudt[kind][h] = new(big.Int)
+	}
+	sudt[kind][h].Add(sudt[kind][h], sudtAmount(c.data))
+}
+
+// checkOutflow checks that we spend no more CKBytes than the transaction moves
+// into the channel plus the maximum fee, and no more SUDTs than it moves into
+// the channel.
+func (b *txBalance) checkOutflow() error {
+	if b.ckbIn[ownCell] > b.ckbOut[ownCell] {
+		outflow := b.ckbIn[ownCell] - b.ckbOut[ownCell]
+		locked := b.ckbOut[channelCell] + b.ckbOut[fundsCell]
+		released := b.ckbIn[channelCell] + b.ckbIn[fundsCell]
+		allowed := MaxTransactionFee
+		if locked > released {
+			allowed += locked - released
+		}
+		if outflow > allowed {
+			return fmt.Errorf("transaction spends %d Shannon of our funds, but at most %d Shannon are expected", outflow, allowed)
+		}
+	}
+	for h, in := range b.sudtIn[ownCell] {
+		outflow := new(big.Int).Sub(in, amountOf(b.sudtOut[ownCell], h))
+		locked := new(big.Int).Sub(amountOf(b.sudtOut[fundsCell], h), amountOf(b.sudtIn[fundsCell], h))
+		if outflow.Sign() > 0 && outflow.Cmp(locked) > 0 {
+			return fmt.Errorf("transaction spends %v of our SUDT %s, but only locks %v in the channel", outflow, h, locked)
+		}
+	}
+	return nil
+}
+
+// checkChannelCell checks that the channel cell created by the transaction
+// holds no more CKBytes than needed. The channel cell of a new channel has to
+// have exactly its occupied capacity, and an updated channel cell keeps the
+// capacity of the consumed one. Otherwise, our funds could be moved into the
+// channel cell, of which the capacity is paid out to the first participant.
+func checkChannelCell(in, out *cell) error {
+	if out == nil {
+		return nil
+	}
+	expected := out.output.OccupiedCapacity(out.data)
+	if in != nil {
+		expected = in.output.Capacity
+	}
+	if out.output.Capacity != expected {
+		return fmt.Errorf("channel cell has a capacity of %d Shannon, but %d Shannon are expected", out.output.Capacity, expected)
+	}
+	return nil
+}
+
+// checkFunding checks that the funds which the transaction locks in the
+// channel are exactly the balances of participant idx in the channel's state.
+func checkFunding(b *txBalance, channelOutput *cell, idx int) error {
+	status, err := molecule.ChannelStatusFromSlice(channelOutput.data, false)
+	if err != nil {
+		return fmt.Errorf("decoding channel status: %w", err)
+	}
+	bals := status.State().Balances()
+	ckb := bals.Ckbytes().Nth0()
+	if idx == 1 {
+		ckb = bals.Ckbytes().Nth1()
+	}
+	// Every SUDT is locked in its own funds cell with the SUDT's maximum
+	// capacity, even if our balance of the SUDT is zero.
+	expected := molecule2.UnpackUint64(ckb)
+	sudts := bals.Sudts()
+	expectedSUDTs := make(map[types.Hash]*big.Int, sudts.Len())
+	for i := uint(0); i < sudts.Len(); i++ {
+		sudt := sudts.Get(i)
+		expected += molecule2.UnpackUint64(sudt.Asset().MaxCapacity())
+		amount := sudt.Distribution().Nth0()
+		if idx == 1 {
+			amount = sudt.Distribution().Nth1()
+		}
+		expectedSUDTs[types.UnpackScript(sudt.Asset().TypeScript()).Hash()] = molecule2.UnpackUint128(amount).Big()
+	}
+	if got := b.ckbOut[fundsCell]; got != expected {
+		return fmt.Errorf("transaction locks %d Shannon in the channel, but our funding is %d Shannon", got, expected)
+	}
+	for h, got := range b.sudtOut[fundsCell] {
+		if _, ok := expectedSUDTs[h]; !ok && got.Sign() != 0 {
+			return fmt.Errorf("transaction locks SUDT %s which is not part of the channel", h)
+		}
+	}
+	for h, want := range expectedSUDTs {
+		if got := amountOf(b.sudtOut[fundsCell], h); got.Cmp(want) != 0 {
+			return fmt.Errorf("transaction locks %v of SUDT %s in the channel, but our funding is %v", got, h, want)
+		}
+	}
+	return nil
+}
+
+// sudtAmount returns the SUDT amount stored in the data of a SUDT cell, which
+// is a little endian uint128.
+func sudtAmount(data []byte) *big.Int {
+	if len(data) < 16 {
+		return new(big.Int)
+	}
+	be := make([]byte, 16)
+	for i := range be {
+		be[i] = data[15-i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func amountOf(amounts map[types.Hash]*big.Int, h types.Hash) *big.Int {
+	if a, ok := amounts[h]; ok {
+		return a
+	}
+	return new(big.Int)
+}
@@ -0,0 +1,203 @@
+package wallet_service_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/backend"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-ckb-backend/encoding"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/wallet_service"
+)
+
+const ckbyte = 100_000_000
+
+var deployment = backend.Deployment{
+	PCTSCodeHash: types.Hash{1},
+	PCTSHashType: types.HashTypeData1,
+	PCLSCodeHash: types.Hash{2},
+	PCLSHashType: types.HashTypeData1,
+	PFLSCodeHash: types.Hash{3},
+	PFLSHashType: types.HashTypeData1,
+}
+
+func TestTxPolicy_Open(t *testing.T) {
+	env := newPolicyEnv(t)
+	// We fund 500 CKBytes and pay for the channel cell.
+	status := env.status(t, 500, 400)
+	channelCapacity := env.channelCapacity(status)
+	env.input(env.own, 1000*ckbyte, nil)
+	env.output(env.fundsLock, 500*ckbyte, nil)
+	env.output(env.pcls, channelCapacity, env.pcts, status)
+	env.output(env.own, 500*ckbyte-channelCapacity-1000, nil)
+	require.NoError(t, env.check())
+
+	// Locking more than our balance in the channel is refused.
+	env.outputs[0].Capacity = 600 * ckbyte
+	env.outputs[2].Capacity = 400*ckbyte - channelCapacity - 1000
+	require.ErrorContains(t, env.check(), "our funding is")
+
+	// So is moving our funds into the channel cell, which is paid out to the
+	// first participant.
+	env.outputs[0].Capacity = 500 * ckbyte
+	env.outputs[1].Capacity = channelCapacity + 100*ckbyte
+	require.ErrorContains(t, env.check(), "channel cell has a capacity")
+}
+
+func TestTxPolicy_Dispute(t *testing.T) {
+	env := newPolicyEnv(t)
+	status := env.status(t, 500, 400)
+	channelCapacity := env.channelCapacity(status)
+	env.input(env.pcls, channelCapacity, env.pcts, status)
+	env.input(env.own, 10*ckbyte, nil)
+	env.output(env.pcls, channelCapacity, env.pcts, status)
+	env.output(env.own, 10*ckbyte-1000, nil)
+	require.NoError(t, env.check())
+
+	// The channel cell must not be inflated from our inputs.
+	env.outputs[0].Capacity += 5 * ckbyte
+	env.outputs[1].Capacity -= 5 * ckbyte
+	require.ErrorContains(t, env.check(), "channel cell has a capacity")
+}
+
+func TestTxPolicy_UnexpectedScripts(t *testing.T) {
+	env := newPolicyEnv(t)
+	env.input(env.own, 1000*ckbyte, nil)
+	env.output(env.peer, 1000*ckbyte-1000, nil)
+	require.ErrorContains(t, env.check(), "output 0: unexpected lock script")
+
+	env = newPolicyEnv(t)
+	env.input(env.own, 1000*ckbyte, nil)
+	env.output(env.own, 1000*ckbyte-1000, &types.Script{CodeHash: types.Hash{4}, HashType: types.HashTypeData1})
+	require.ErrorContains(t, env.check(), "output 0: unexpected type script")
+}
+
+func TestTxPolicy_Fee(t *testing.T) {
+	env := newPolicyEnv(t)
+	env.input(env.own, 1000*ckbyte, nil)
+	env.output(env.own, 1000*ckbyte-wallet_service.MaxTransactionFee, nil)
+	require.NoError(t, env.check())
+
+	env.outputs[0].Capacity--
+	require.ErrorContains(t, env.check(), "spends")
+}
+
+func TestTxPolicy_Close(t *testing.T) {
+	env := newPolicyEnv(t)
+	status := env.status(t, 500, 400)
+	env.input(env.pcls, 300*ckbyte, env.pcts, status)
+	env.input(env.fundsLock, 500*ckbyte, nil)
+	env.input(env.fundsLock, 400*ckbyte, nil)
+	env.input(env.own, 10*ckbyte, nil)
+	env.output(env.own, 800*ckbyte, nil)
+	env.output(env.peer, 400*ckbyte, nil)
+	env.output(env.own, 10*ckbyte-1000, nil)
+	require.NoError(t, env.check())
+
+	// Participants may only be paid out if the channel is closed.
+	env.output(env.pcls, 300*ckbyte, env.pcts, status)
+	require.ErrorContains(t, env.check(), "without closing the channel")
+}
+
+// policyEnv builds transactions between us and a peer.
+type policyEnv struct {
+	own, peer, pcls, pcts, fundsLock *types.Script
+
+	policy  *wallet_service.TxPolicy
+	prev    *types.Transaction
+	inputs  []*types.CellInput
+	outputs []*types.CellOutput
+	data    [][]byte
+}
+
+func newPolicyEnv(t *testing.T) *policyEnv {
+	parts := make([]gpwallet.Address, 2)
+	for i := range parts {
+		key, err := secp256k1.GeneratePrivateKey()
+		require.NoError(t, err)
+		parts[i], err = address.NewDefaultParticipant(key.PubKey())
+		require.NoError(t, err)
+	}
+	params := channel.NewParamsUnsafe(10, parts, channel.NoApp(), big.NewInt(1), true, false)
+	packedParams, err := encoding.PackChannelParameters(params)
+	require.NoError(t, err)
+	constants := molecule.NewChannelConstantsBuilder().Params(packedParams).Build()
+	pcts := &types.Script{
+		CodeHash: deployment.PCTSCodeHash,
+		HashType: deployment.PCTSHashType,
+		Args:     constants.AsSlice(),
+	}
+	env := &policyEnv{
+		own:  address.AsParticipant(parts[0]).PaymentScript,
+		peer: address.AsParticipant(parts[1]).PaymentScript,
+		pcls: &types.Script{CodeHash: deployment.PCLSCodeHash, HashType: deployment.PCLSHashType},
+		pcts: pcts,
+		fundsLock: &types.Script{
+			CodeHash: deployment.PFLSCodeHash,
+			HashType: deployment.PFLSHashType,
+			Args:     pcts.Hash().Bytes(),
+		},
+		prev: &types.Transaction{Version: 1},
+	}
+	env.policy = wallet_service.NewTxPolicy(deployment, env.own, env)
+	return env
+}
+
+// status returns the channel status with the given CKBytes balances.
+func (env *policyEnv) status(t *testing.T, bals ...int64) []byte {
+	ckb := asset.NewCKBytesAsset()
+	alloc := channel.NewAllocation(2, ckb)
+	alloc.SetAssetBalances(ckb, []channel.Bal{big.NewInt(bals[0] * ckbyte), big.NewInt(bals[1] * ckbyte)})
+	packed, err := encoding.PackChannelState(&channel.State{Allocation: *alloc, App: channel.NoApp(), Data: channel.NoData()})
+	require.NoError(t, err)
+	status := molecule.NewChannelStatusBuilder().State(packed).Funded(encoding.True).Build()
+	return status.AsSlice()
+}
+
+// channelCapacity returns the occupied capacity of a channel cell with the
+// given status.
+func (env *policyEnv) channelCapacity(status []byte) uint64 {
+	return (&types.CellOutput{Lock: env.pcls, Type: env.pcts}).OccupiedCapacity(status)
+}
+
+func (env *policyEnv) input(lock *types.Script, capacity uint64, typ *types.Script, data ...[]byte) {
+	env.prev.Outputs = append(env.prev.Outputs, &types.CellOutput{Capacity: capacity, Lock: lock, Type: typ})
+	env.prev.OutputsData = append(env.prev.OutputsData, cellData(data))
+	env.inputs = append(env.inputs, &types.CellInput{PreviousOutput: &types.OutPoint{
+		TxHash: types.Hash{0xff},
+		Index:  uint32(len(env.prev.Outputs) - 1),
+	}})
+}
+
+func (env *policyEnv) output(lock *types.Script, capacity uint64, typ *types.Script, data ...[]byte) {
+	env.outputs = append(env.outputs, &types.CellOutput{Capacity: capacity, Lock: lock, Type: typ})
+	env.data = append(env.data, cellData(data))
+}
+
+func cellData(data [][]byte) []byte {
+	if len(data) == 0 {
+		return []byte{}
+	}
+	return data[0]
+}
+
+func (env *policyEnv) check() error {
+	tx := &types.Transaction{Version: 0, Inputs: env.inputs, Outputs: env.outputs, OutputsData: env.data}
+	return env.policy.Check(context.Background(), tx)
+}
+
+// GetTransaction returns the transaction which created the inputs.
+func (env *policyEnv) GetTransaction(_ context.Context, hash types.Hash) (*types.TransactionWithStatus, error) {
+	if hash != (types.Hash{0xff}) {
+		return nil, nil
+	}
+	return &types.TransactionWithStatus{Transaction: env.prev}, nil
+}
@@ -3,12 +3,36 @@ package wallet_service
 import (
 	"errors"
 	"fmt"
+	"math/big"
 
 	"perun.network/channel-service/rpc/proto"
 	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-nervos-demo/deployment"
 )
 
-func verifyOpenChannelRequest(in *proto.OpenChannelRequest) error {
+// ChallengeDurationBounds is the policy for the on-chain challenge duration of
+// channels in seconds. A zero Max means that there is no upper bound.
+type ChallengeDurationBounds struct {
+	Min uint64
+	Max uint64
+}
+
+// Check returns an error if the challenge duration d violates the bounds.
+func (b ChallengeDurationBounds) Check(d uint64) error {
+	if d == 0 {
+		return errors.New("Challenge duration must be positive")
+	}
+	if d < b.Min {
+		return fmt.Errorf("Challenge duration %ds is below the minimum of %ds", d, b.Min)
+	}
+	if b.Max != 0 && d > b.Max {
+		return fmt.Errorf("Challenge duration %ds exceeds the maximum of %ds", d, b.Max)
+	}
+	return nil
+}
+
+func verifyOpenChannelRequest(in *proto.OpenChannelRequest, bounds ChallengeDurationBounds) error {
 	prop := in.Proposal
 
 	if prop == nil {
@@ -25,6 +49,14 @@ func verifyOpenChannelRequest(in *proto.OpenChannelRequest) error {
 		return errors.New("Missing base channel proposal")
 	}
 
+	if err := bounds.Check(baseProp.ChallengeDuration); err != nil {
+		return err
+	}
+
+	if len(baseProp.App) != 0 {
+		return errors.New("Only payment channels without app are supported")
+	}
+
 	if baseProp.InitBals == nil {
 		return errors.New("Missing initial balances")
 	}
@@ -39,6 +71,9 @@ func verifyOpenChannelRequest(in *proto.OpenChannelRequest) error {
 	if err := verifyAllocation(baseProp.InitBals); err != nil {
 		return err
 	}
+	if err := verifyFunding(baseProp.InitBals); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -67,3 +102,31 @@ func verifyAllocation(allocation *protobuf.Allocation) error {
 
 	return nil
 }
+
+// verifyFunding checks that the initial allocation of a channel can be funded.
+// Each participant funds its own cell, which has to hold at least
+// deployment.PFLSMinCapacity CKBytes, also if the participant contributes
+// only SUDTs or nothing at all.
+func verifyFunding(allocation *protobuf.Allocation) error {
+	minCapacity := new(big.Int).SetUint64(deployment.PFLSMinCapacity)
+	hasCKBytes := false
+	for i, a := range allocation.Assets {
+		var ckbAsset asset.Asset
+		if err := ckbAsset.UnmarshalBinary(a); err != nil {
+			return fmt.Errorf("Invalid asset %d: %w", i, err)
+		}
+		if !ckbAsset.IsCKBytes {
+			continue
+		}
+		hasCKBytes = true
+		for part, bal := range protobuf.ToBalance(allocation.Balances.Balances[i]) {
+			if bal.Cmp(minCapacity) < 0 {
+				return fmt.Errorf("CKBytes contribution %v of participant %d is below the minimum funding cell capacity %d", bal, part, uint64(deployment.PFLSMinCapacity))
+			}
+		}
+	}
+	if !hasCKBytes {
+		return fmt.Errorf("Missing CKBytes contributions of at least the minimum funding cell capacity %d", uint64(deployment.PFLSMinCapacity))
+	}
+	return nil
+}
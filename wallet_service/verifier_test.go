@@ -0,0 +1,48 @@
+package wallet_service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire/protobuf"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-nervos-demo/deployment"
+)
+
+func TestVerifyFunding(t *testing.T) {
+	const min = deployment.PFLSMinCapacity
+	ckb := asset.NewCKBytesAsset()
+	sudt := asset.NewSUDTAsset(asset.NewSUDT(types.Script{CodeHash: types.Hash{1}, HashType: types.HashTypeData1, Args: []byte{1}}, 142*100_000_000))
+
+	for _, tc := range []struct {
+		name   string
+		assets []channel.Asset
+		bals   [][]int64
+		err    string
+	}{
+		{"minimum", []channel.Asset{ckb}, [][]int64{{min, min}}, ""},
+		{"above minimum", []channel.Asset{ckb}, [][]int64{{min + 1, 2 * min}}, ""},
+		{"zero", []channel.Asset{ckb}, [][]int64{{min, 0}}, "below the minimum"},
+		{"below minimum", []channel.Asset{ckb}, [][]int64{{min - 1, min}}, "below the minimum"},
+		{"SUDT with minimum", []channel.Asset{sudt, ckb}, [][]int64{{10, 0}, {min, min}}, ""},
+		{"SUDT only", []channel.Asset{sudt}, [][]int64{{10, 10}}, "Missing CKBytes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			alloc := channel.NewAllocation(2, tc.assets...)
+			for i, bals := range tc.bals {
+				alloc.Balances[i] = []channel.Bal{big.NewInt(bals[0]), big.NewInt(bals[1])}
+			}
+			protoAlloc, err := protobuf.FromAllocation(*alloc)
+			require.NoError(t, err)
+			err = verifyFunding(protoAlloc)
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
@@ -2,27 +2,31 @@ package wallet_service
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"net"
 	"os"
+	"time"
 
 	"polycry.pt/poly-go/sync"
 
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/crypto/blake2b"
 	"github.com/nervosnetwork/ckb-sdk-go/v2/transaction"
 	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
-	"google.golang.org/grpc"
+	"golang.org/x/crypto/sha3"
 	"perun.network/channel-service/rpc/proto"
 	"perun.network/go-perun/channel"
 	"perun.network/go-perun/client"
+	gpwallet "perun.network/go-perun/wallet"
 	"perun.network/go-perun/wire/protobuf"
 	"perun.network/perun-ckb-backend/backend"
-	"perun.network/perun-ckb-backend/channel/asset"
-	"perun.network/perun-ckb-backend/wallet"
+	_ "perun.network/perun-ckb-backend/channel" // Registers the CKB channel backend.
 	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/audit"
+	"perun.network/perun-nervos-demo/signer"
+	"perun.network/perun-nervos-demo/wallet_service/walletpb"
 )
 
 const (
@@ -31,24 +35,50 @@ const (
 
 // MyWalletService implements the wallet API.
 type MyWalletService struct {
-	account    *wallet.Account
-	privateKey *secp256k1.PrivateKey
+	signer     signer.Signer
 	network    types.Network
 	stateMtx   sync.Mutex
-	state      *channel.State
-	logger     *log.Logger
-	server     *grpc.Server
-
-	signer backend.Signer
-
-	onUpdate func(from, to *channel.State)
-	onClose  func()
+	states     map[channel.ID]*channel.State
+	signed     map[channel.ID]*stateSummary // Latest state signed per channel.
+	authorized map[channel.ID]*stateSummary // Updates proposed by the client.
+	opening    map[channel.ID]*stateSummary // Initial states of approved channels.
+	proposals  []*ownProposal               // Channels proposed by the client.
+
+	approvalMtx     sync.Mutex
+	approver        Approver
+	approvalTimeout time.Duration
+	store           *store
+	bounds          ChallengeDurationBounds
+	policy          *TxPolicy
+	auditLog        *audit.Log
+	caller          string // Identity of the authorized channel service.
+	client          string // Identity of the authorized client.
+	events          eventFeed
+	chain           Chain
+	ownLock         *types.Script
+	sudts           []types.Script // SUDTs of the deployment, ordered by hash.
+	logger          *log.Logger
+
+	authMtx        sync.Mutex
+	authorizations map[uint64]func() // Revocations of the client's authorizations.
+	nextAuth       uint64
+
+	settleMtx sync.Mutex
+	watching  map[channel.ID]struct{} // Channels whose settlement is awaited.
+	done      chan struct{}           // Closed when the wallet service is closed.
 
 	proto.UnimplementedWalletServiceServer
 }
 
-// NewWalletService creates a new wallet service.
-func NewWalletService(name string, acc *wallet.Account, privKey *secp256k1.PrivateKey, network types.Network) *MyWalletService {
+// NewWalletService creates a new wallet service. All signatures are created
+// by sgn, so the service never holds the private key. The participants and
+// states of the channels are kept in the LevelDB directory dbDir, from which
+// open channels are reloaded, or only in memory if dbDir is empty. Proposals
+// with a challenge duration outside of bounds are rejected. Transactions are
+// only signed if they comply with the TxPolicy of the deployment, for which
+// their inputs are looked up at chain. The indexer of chain also provides the
+// on-chain balances of the account.
+func NewWalletService(name string, sgn signer.Signer, network types.Network, dbDir string, bounds ChallengeDurationBounds, deployment backend.Deployment, chain Chain) (*MyWalletService, error) {
 	file, err := os.OpenFile(fmt.Sprintf(logFile, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		panic(err)
@@ -57,84 +87,224 @@ func NewWalletService(name string, acc *wallet.Account, privKey *secp256k1.Priva
 
 	logger := log.New(file, "wallet_service: ", log.LstdFlags)
 
-	ckbAddr := address.AsParticipant(acc.Address()).ToCKBAddress(network)
-	signer := backend.NewSignerInstance(ckbAddr, *privKey, network)
-
-	return &MyWalletService{
-		account:    acc,
-		privateKey: privKey,
-		network:    network,
-		logger:     logger,
-		signer:     signer,
+	st, err := openStore(dbDir)
+	if err != nil {
+		return nil, fmt.Errorf("opening wallet store: %w", err)
 	}
-}
-
-// NewWalletServiceServer creates a new wallet service server with the url.
-func NewWalletServiceServer(name string, acc *wallet.Account, privKey *secp256k1.PrivateKey, network types.Network, url string, wg *sync.WaitGroup) (*MyWalletService, error) {
-	lis, err := net.Listen("tcp", url)
+	stored, err := st.states()
 	if err != nil {
-		return nil, err
+		st.close()
+		return nil, fmt.Errorf("loading channel states: %w", err)
+	}
+	states := make(map[channel.ID]*channel.State, len(stored))
+	for _, state := range stored {
+		states[state.ID] = state
 	}
-	ws := NewWalletService(name, acc, privKey, network)
-	var opts []grpc.ServerOption
-	s := grpc.NewServer(opts...)
-	proto.RegisterWalletServiceServer(s, ws)
 
-	go func() {
+	part := address.AsParticipant(sgn.Address())
 
-		ws.logger.Println("wallet service listening on", url)
-		err := s.Serve(lis)
-		if err != nil {
-			panic(err)
+	wsc := &MyWalletService{
+		signer:     sgn,
+		network:    network,
+		states:     states,
+		signed:     make(map[channel.ID]*stateSummary),
+		authorized: make(map[channel.ID]*stateSummary),
+		opening:    make(map[channel.ID]*stateSummary),
+		store:      st,
+		bounds:     bounds,
+		policy:     NewTxPolicy(deployment, part.PaymentScript, chain),
+		chain:      chain,
+		ownLock:    part.PaymentScript,
+		sudts:      sortedSUDTs(deployment.SUDTs),
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+	// Channels which ended before a restart are still settled.
+	for id, state := range states {
+		if settling, err := st.isSettling(id); state.IsFinal || (err == nil && settling) {
+			wsc.awaitSettlement(id)
 		}
-	}()
-	ws.server = s
-	wg.Add(1)
-	return ws, nil
-}
-
-func (ws *MyWalletService) Shutdown(wg *sync.WaitGroup) {
-	defer wg.Done() // Decrease the wait group counter
-	ws.logger.Println("Shutting down wallet service...")
-	ws.server.Stop()
-	ws.logger.Println("Wallet service stopped gracefully")
+	}
+	return wsc, nil
 }
 
-// SetOnUpdate sets the function to be called when the state is updated.
-func (wsc *MyWalletService) SetOnUpdate(onUpdate func(from, to *channel.State)) {
-	wsc.onUpdate = onUpdate
+// Close closes the store and the audit log of the wallet service and stops
+// watching the settlement of channels.
+func (wsc *MyWalletService) Close() {
+	if wsc.done != nil {
+		close(wsc.done)
+	}
+	if err := wsc.store.close(); err != nil {
+		wsc.logger.Println("Error closing wallet store:", err)
+	}
+	if wsc.auditLog != nil {
+		if err := wsc.auditLog.Close(); err != nil {
+			wsc.logger.Println("Error closing audit log:", err)
+		}
+	}
 }
 
-// OpenChannel opens a channel.
+// OpenChannel decides on an incoming channel proposal. Valid proposals are
+// accepted if the approver agrees, otherwise the channel service gets the
+// reason of the rejection.
 func (wsc *MyWalletService) OpenChannel(ctx context.Context, in *proto.OpenChannelRequest) (*proto.OpenChannelResponse, error) {
 	wsc.logger.Println("wallet: openChannelRequest")
-	err := verifyOpenChannelRequest(in)
+	err := verifyOpenChannelRequest(in, wsc.bounds)
 	if err != nil {
-		return nil, fmt.Errorf("open channel: %w", err)
+		wsc.logger.Println("Rejecting invalid proposal:", err)
+		wsc.audit(audit.Entry{Type: audit.TypeProposal, Reason: err.Error()})
+		wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_PROPOSAL_REJECTED, Reason: err.Error()})
+		return openChannelRejected(err.Error()), nil
+	}
+	prop, err := toProposal(in.Proposal, wsc.sudts)
+	if err != nil {
+		wsc.logger.Println("Rejecting invalid proposal:", err)
+		wsc.audit(audit.Entry{Type: audit.TypeProposal, Reason: err.Error()})
+		wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_PROPOSAL_REJECTED, Reason: err.Error()})
+		return openChannelRejected(err.Error()), nil
+	}
+	wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_PROPOSAL, Peer: prop.Peer.String(), ChallengeDuration: prop.ChallengeDuration})
+	if d := wsc.approve(ctx, prop); !d.Accept {
+		wsc.logger.Println("Proposal rejected:", d.Reason)
+		wsc.audit(audit.Entry{Type: audit.TypeProposal, Reason: d.Reason})
+		wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_PROPOSAL_REJECTED, Peer: prop.Peer.String(), Reason: d.Reason})
+		return openChannelRejected(d.Reason), nil
 	}
-	return openChannelAccepted()
-
-}
-
-func openChannelAccepted() (*proto.OpenChannelResponse, error) {
 	nonceShare := client.WithRandomNonce()["nonce"]
 	nonceShareBytes, ok := nonceShare.([32]byte)
 	if !ok {
 		return nil, errors.New("nonce share is not a byte array")
 	}
+	params, err := wsc.proposalParams(in.Proposal, nonceShareBytes)
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	if err := wsc.SetParticipants(params.ID(), params.Parts); err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	id := params.ID()
+	initial, err := summarize(&channel.State{ID: id, Allocation: *prop.Allocation, App: channel.NoApp(), Data: channel.NoData()})
+	if err != nil {
+		return nil, fmt.Errorf("open channel: %w", err)
+	}
+	wsc.stateMtx.Lock()
+	wsc.opening[id] = initial
+	wsc.stateMtx.Unlock()
+	wsc.audit(audit.Entry{Type: audit.TypeProposal, Channel: hex.EncodeToString(id[:]), Accepted: true})
+	wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_PROPOSAL_ACCEPTED, ChannelId: id[:], Peer: prop.Peer.String()})
+	return openChannelAccepted(nonceShareBytes)
+
+}
+
+func openChannelAccepted(nonceShare [32]byte) (*proto.OpenChannelResponse, error) {
 	return &proto.OpenChannelResponse{
 		Msg: &proto.OpenChannelResponse_NonceShare{
-			NonceShare: nonceShareBytes[:],
+			NonceShare: nonceShare[:],
 		}}, nil
 }
 
+func openChannelRejected(reason string) *proto.OpenChannelResponse {
+	return &proto.OpenChannelResponse{
+		Msg: &proto.OpenChannelResponse_Rejected{
+			Rejected: &proto.Rejected{Reason: reason},
+		}}
+}
+
+// toProposal converts a verified proposal for approval.
+func toProposal(prop *protobuf.LedgerChannelProposalMsg, sudts []types.Script) (*Proposal, error) {
+	peer, err := protobuf.ToWalletAddr(prop.Participant)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid proposer address: %w", err)
+	}
+	alloc, err := toCKBAllocation(prop.BaseChannelProposal.InitBals, sudts)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid allocation: %w", err)
+	}
+	return &Proposal{
+		Peer:              peer,
+		Allocation:        alloc,
+		ChallengeDuration: prop.BaseChannelProposal.ChallengeDuration,
+	}, nil
+}
+
+// proposalParams computes the parameters of the channel that is created if we
+// accept the given proposal with our nonce share. The proposer always has
+// index 0.
+func (wsc *MyWalletService) proposalParams(prop *protobuf.LedgerChannelProposalMsg, nonceShare [32]byte) (*channel.Params, error) {
+	proposer, err := protobuf.ToWalletAddr(prop.Participant)
+	if err != nil {
+		return nil, fmt.Errorf("proposer address: %w", err)
+	}
+	baseProp := prop.BaseChannelProposal
+	// The nonce is computed from both nonce shares like go-perun does.
+	hasher := sha3.New256()
+	hasher.Write(baseProp.NonceShare)
+	hasher.Write(nonceShare[:])
+	nonce := channel.NonceFromBytes(hasher.Sum(nil))
+	parts := []gpwallet.Address{proposer, wsc.signer.Address()}
+	return channel.NewParamsUnsafe(baseProp.ChallengeDuration, parts, channel.NoApp(), nonce, true, false), nil
+}
+
+// Participants returns the participants of the channel with the given ID in
+// channel order, or nil if the participants are unknown. The wallet service
+// records the participants of the channels which it accepted, and of the
+// channels which we proposed once it signed their initial state.
+func (wsc *MyWalletService) Participants(id channel.ID) []gpwallet.Address {
+	parts, err := wsc.store.participants(id)
+	if err != nil {
+		wsc.logger.Printf("Error reading participants of channel %x: %v", id, err)
+		return nil
+	}
+	return parts
+}
+
+// SetParticipants records the participants of the channel with the given ID.
+func (wsc *MyWalletService) SetParticipants(id channel.ID, parts []gpwallet.Address) error {
+	if err := wsc.store.putParticipants(id, parts); err != nil {
+		return fmt.Errorf("storing participants: %w", err)
+	}
+	return nil
+}
+
+// SignMessage signs a channel state. It refuses to sign if the state is not a
+// valid successor of the latest known state of the channel.
 func (wsc *MyWalletService) SignMessage(ctx context.Context, in *proto.SignMessageRequest) (*proto.SignMessageResponse, error) {
 	wsc.logger.Println("wallet: signMessageRequest")
 
-	signedMsg, err := wsc.account.SignData(in.Data)
+	hash := blake2b.Blake256(in.Data)
+	entry := audit.Entry{Type: audit.TypeSignState, Payload: hex.EncodeToString(hash)}
+	state, err := decodeState(in.Data)
+	if err == nil {
+		entry.Channel, entry.Version = hex.EncodeToString(state.id[:]), state.version
+		err = wsc.verifySigning(state)
+	}
+	if err != nil {
+		wsc.logger.Println("Refusing to sign message:", err)
+		entry.Reason = err.Error()
+		wsc.audit(entry)
+		return &proto.SignMessageResponse{
+			Msg: &proto.SignMessageResponse_Rejected{
+				Rejected: &proto.Rejected{Reason: err.Error()},
+			},
+		}, nil
+	}
+
+	entry.Accepted = true
+	err = wsc.audit(entry)
+	var signedMsg []byte
+	if err == nil {
+		signedMsg, err = wsc.signer.SignData(ctx, in.Data)
+	}
 	if err != nil {
+		// The signer may be a separate daemon, which can be unreachable.
 		wsc.logger.Println("Error signing message", err)
+		return &proto.SignMessageResponse{
+			Msg: &proto.SignMessageResponse_Rejected{
+				Rejected: &proto.Rejected{Reason: err.Error()},
+			},
+		}, nil
 	}
+	wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_SIGNED, ChannelId: state.id[:], Version: state.version, Payload: hash})
 	return &proto.SignMessageResponse{
 		Msg: &proto.SignMessageResponse_Signature{
 			Signature: signedMsg,
@@ -150,10 +320,35 @@ func (wsc *MyWalletService) SignTransaction(ctx context.Context, in *proto.SignT
 	if err != nil {
 		return nil, fmt.Errorf("sign transaction: %w", err)
 	}
-	wsc.logger.Printf("Signing transaction: %s\n", string(in.Transaction))
-	signedTX, err := wsc.signer.SignTransaction(&tx)
+	if tx.TxView == nil {
+		return nil, errors.New("sign transaction: missing transaction")
+	}
+	txHash := tx.TxView.ComputeHash()
+	entry := audit.Entry{Type: audit.TypeSignTransaction, Payload: hex.EncodeToString(txHash[:])}
+	if err := wsc.policy.Check(ctx, tx.TxView); err != nil {
+		wsc.logger.Printf("Refusing to sign transaction %v: %v\n", txHash, err)
+		entry.Reason = err.Error()
+		wsc.audit(entry)
+		return &proto.SignTransactionResponse{
+			Msg: &proto.SignTransactionResponse_Rejected{
+				Rejected: &proto.Rejected{Reason: err.Error()},
+			},
+		}, nil
+	}
+	wsc.logger.Printf("Signing transaction %v\n", txHash)
+	entry.Accepted = true
+	err = wsc.audit(entry)
+	var signedTX *types.Transaction
+	if err == nil {
+		signedTX, err = wsc.signer.SignTransaction(ctx, &tx)
+	}
 	if err != nil {
-		wsc.logger.Println("Error signing message", err)
+		wsc.logger.Println("Error signing transaction", err)
+		return &proto.SignTransactionResponse{
+			Msg: &proto.SignTransactionResponse_Rejected{
+				Rejected: &proto.Rejected{Reason: err.Error()},
+			},
+		}, nil
 	}
 
 	signedTXBytes, err := json.Marshal(signedTX)
@@ -161,7 +356,8 @@ func (wsc *MyWalletService) SignTransaction(ctx context.Context, in *proto.SignT
 		return nil, fmt.Errorf("sign transaction: %w", err)
 
 	}
-	wsc.logger.Printf("Signed transaction: %s\n", string(signedTXBytes))
+	wsc.recordChannelCell(tx.TxView)
+	wsc.publish(&walletpb.Event{Type: walletpb.EventType_EVENT_TYPE_SIGNED, Payload: txHash[:]})
 	return &proto.SignTransactionResponse{
 		Msg: &proto.SignTransactionResponse_Transaction{
 			Transaction: signedTXBytes,
@@ -172,45 +368,314 @@ func (wsc *MyWalletService) SignTransaction(ctx context.Context, in *proto.SignT
 func (wsc *MyWalletService) UpdateNotification(ctx context.Context, in *proto.UpdateNotificationRequest) (*proto.UpdateNotificationResponse, error) {
 	wsc.logger.Printf("wallet: updateNotificationRequest: state %v\n", in.State)
 
-	state, err := toCKBState(in.State)
+	state, err := toCKBState(in.State, wsc.sudts)
 	if err != nil {
 		return nil, fmt.Errorf("update notification: %w", err)
 	}
 	wsc.logger.Printf("wallet: updateNotificationRequest: balance %v\n", state.Allocation.Balances)
-	wsc.onUpdate(nil, state)
-
-	wsc.setState(state)
+	if closed, err := wsc.store.isClosed(state.ID); err != nil {
+		return nil, fmt.Errorf("update notification: %w", err)
+	} else if closed {
+		// The final state may be notified again after the channel was settled.
+		accepted, err := wsc.isLatest(state)
+		if err != nil {
+			return nil, fmt.Errorf("update notification: %w", err)
+		}
+		wsc.logger.Printf("Update of closed channel %x accepted: %t", state.ID, accepted)
+		return &proto.UpdateNotificationResponse{Accepted: accepted}, nil
+	}
+	entry := audit.Entry{Type: audit.TypeUpdate, Channel: hex.EncodeToString(state.ID[:]), Version: state.Version}
+	committed, err := wsc.verifyNotification(state)
+	if err != nil {
+		wsc.logger.Println("Rejecting update:", err)
+		entry.Reason = err.Error()
+		wsc.audit(entry)
+		return &proto.UpdateNotificationResponse{
+			Accepted: false,
+		}, nil
+	}
+	entry.Accepted = true
+	wsc.audit(entry)
+	if committed {
+		e, err := wsc.updateEvent(wsc.getState(state.ID), state)
+		wsc.setState(state)
+		if err != nil {
+			wsc.logger.Println("Error creating update event:", err)
+		} else {
+			wsc.publish(e)
+		}
+		// The final state ends the channel, which is closed once it is settled
+		// on-chain. Until then, only the final state is accepted again.
+		if state.IsFinal {
+			wsc.awaitSettlement(state.ID)
+		}
+	}
 
 	return &proto.UpdateNotificationResponse{
 		Accepted: true,
 	}, nil
 }
 
-func (wsc *MyWalletService) GetAssets(ctx context.Context, in *proto.GetAssetsRequest) (*proto.GetAssetsResponse, error) {
-	return nil, errors.New("not implemented")
+// SetAuditLog sets the log to which all signing requests, decisions on
+// proposals and update notifications are appended. It must be set before the
+// wallet service receives requests.
+func (wsc *MyWalletService) SetAuditLog(l *audit.Log) {
+	wsc.auditLog = l
 }
 
+// SetAuthorizedCaller restricts the requests to the account to callers with
+// the given mutual TLS identity, see package mtls. It must be set before the
+// wallet service receives requests. By default, every caller is accepted.
+func (wsc *MyWalletService) SetAuthorizedCaller(identity string) {
+	wsc.caller = identity
+}
+
+// audit appends the entry to the audit log, if there is one. Signatures are
+// only handed out if their entry was written, other requests are processed
+// even if writing the entry fails.
+func (wsc *MyWalletService) audit(e audit.Entry) error {
+	if wsc.auditLog == nil {
+		return nil
+	}
+	if err := wsc.auditLog.Append(e); err != nil {
+		wsc.logger.Println("Error writing audit log:", err)
+		return fmt.Errorf("audit log unavailable: %w", err)
+	}
+	return nil
+}
+
+// AuthorizeUpdate allows the wallet service to sign the given update of a
+// channel until the returned function is called, even if it decreases our
+// balance. The client authorizes the updates which it proposes.
+func (wsc *MyWalletService) AuthorizeUpdate(state *channel.State) (revoke func(), err error) {
+	summary, err := summarize(state)
+	if err != nil {
+		return nil, err
+	}
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	wsc.authorized[state.ID] = summary
+	return func() {
+		wsc.stateMtx.Lock()
+		defer wsc.stateMtx.Unlock()
+		if wsc.authorized[state.ID] == summary {
+			delete(wsc.authorized, state.ID)
+		}
+	}, nil
+}
+
+// ownProposal is a channel proposed by the client, of which the ID is not
+// known before the peer accepted it.
+type ownProposal struct {
+	parts   []gpwallet.Address
+	initial *stateSummary // Initial state without channel ID.
+}
+
+// AuthorizeProposal allows the wallet service to sign the initial state of a
+// channel with the given participants and initial allocation until the
+// returned function is called. The client authorizes the channels which it
+// proposes. Once the initial state is signed, the participants are recorded
+// for the ID of the new channel.
+func (wsc *MyWalletService) AuthorizeProposal(parts []gpwallet.Address, alloc *channel.Allocation) (revoke func(), err error) {
+	initial, err := summarize(&channel.State{Allocation: *alloc, App: channel.NoApp(), Data: channel.NoData()})
+	if err != nil {
+		return nil, err
+	}
+	prop := &ownProposal{parts: parts, initial: initial}
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	wsc.proposals = append(wsc.proposals, prop)
+	return func() {
+		wsc.stateMtx.Lock()
+		defer wsc.stateMtx.Unlock()
+		wsc.removeProposal(prop)
+	}, nil
+}
+
+func (wsc *MyWalletService) removeProposal(prop *ownProposal) {
+	for i, p := range wsc.proposals {
+		if p == prop {
+			wsc.proposals = append(wsc.proposals[:i], wsc.proposals[i+1:]...)
+			return
+		}
+	}
+}
+
+// verifyOpening checks that the state to is the initial state of a channel
+// which we accepted, or which the client proposed. In the latter case, the
+// channel is recorded as accepted now.
+func (wsc *MyWalletService) verifyOpening(to *stateSummary) error {
+	if to.version != 0 || to.isFinal {
+		return fmt.Errorf("Unknown channel %x", to.id)
+	}
+	if initial, ok := wsc.opening[to.id]; ok {
+		if !initial.equal(to) {
+			return fmt.Errorf("Initial state of channel %x does not match the proposal", to.id)
+		}
+		return nil
+	}
+	for _, prop := range wsc.proposals {
+		if !prop.initial.sameBalances(to) {
+			continue
+		}
+		if err := wsc.SetParticipants(to.id, prop.parts); err != nil {
+			return err
+		}
+		wsc.removeProposal(prop)
+		wsc.opening[to.id] = to
+		return nil
+	}
+	return fmt.Errorf("Channel %x was not proposed or accepted by us", to.id)
+}
+
+// verifySigning checks that we may sign the state to and records it as the
+// latest signed state of its channel.
+func (wsc *MyWalletService) verifySigning(to *stateSummary) error {
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	known, ok := wsc.states[to.id]
+	if !ok {
+		// We learn about a new channel only after signing its initial state.
+		if err := wsc.verifyOpening(to); err != nil {
+			return err
+		}
+		wsc.signed[to.id] = to
+		return nil
+	}
+	from, err := summarize(known)
+	if err != nil {
+		return err
+	}
+	idx, err := wsc.ownIndex(to.id)
+	if err != nil {
+		return err
+	}
+	authorized := wsc.authorized[to.id]
+	ours := authorized != nil && authorized.sameBalances(to)
+	if err := verifyTransition(from, to, idx, ours); err != nil {
+		return err
+	}
+	wsc.signed[to.id] = to
+	return nil
+}
+
+// verifyNotification checks an update notification of the channel service.
+// The channel service notifies us about the updates proposed by the peer,
+// which we may reject, and about the states on which both participants agreed,
+// in which case committed is true.
+func (wsc *MyWalletService) verifyNotification(state *channel.State) (committed bool, err error) {
+	to, err := summarize(state)
+	if err != nil {
+		return false, err
+	}
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	if signed, ok := wsc.signed[state.ID]; ok && signed.equal(to) {
+		delete(wsc.signed, state.ID)
+		delete(wsc.opening, state.ID)
+		return true, nil
+	}
+	known, ok := wsc.states[state.ID]
+	if !ok {
+		// The initial state of a new channel is notified after we signed it.
+		if initial, ok := wsc.opening[state.ID]; !ok || !initial.equal(to) {
+			return false, fmt.Errorf("Unknown channel %x", state.ID)
+		}
+		delete(wsc.opening, state.ID)
+		return true, nil
+	}
+	from, err := summarize(known)
+	if err != nil {
+		return false, err
+	}
+	if from.equal(to) {
+		return true, nil
+	}
+	idx, err := wsc.ownIndex(state.ID)
+	if err != nil {
+		return false, err
+	}
+	return false, verifyTransition(from, to, idx, false)
+}
+
+// ownIndex returns our index in the channel with the given ID.
+func (wsc *MyWalletService) ownIndex(id channel.ID) (int, error) {
+	for i, part := range wsc.Participants(id) {
+		if part.Equal(wsc.signer.Address()) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Not a participant of channel %x", id)
+}
+
+// setState records state as the latest state of its channel. The state is
+// kept in memory even if it cannot be persisted, because both participants
+// already agreed on it.
 func (wsc *MyWalletService) setState(state *channel.State) {
 	wsc.stateMtx.Lock()
 	defer wsc.stateMtx.Unlock()
-	wsc.state = state.Clone()
+	if err := wsc.store.putState(state); err != nil {
+		wsc.logger.Printf("Error storing state of channel %x: %v", state.ID, err)
+	}
+	wsc.states[state.ID] = state.Clone()
 }
 
-func (wsc *MyWalletService) getState() *channel.State {
+// Channels returns the latest states of all open channels, which survive a
+// restart if the wallet service has a database.
+func (wsc *MyWalletService) Channels() []*channel.State {
 	wsc.stateMtx.Lock()
 	defer wsc.stateMtx.Unlock()
-	if wsc.state == nil {
+	states := make([]*channel.State, 0, len(wsc.states))
+	for _, state := range wsc.states {
+		states = append(states, state.Clone())
+	}
+	return states
+}
+
+// History returns all states of the channel with the given ID on which both
+// participants agreed, ordered by version.
+func (wsc *MyWalletService) History(id channel.ID) ([]*channel.State, error) {
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	return wsc.store.history(id)
+}
+
+// isLatest returns whether state equals the latest state in the history of
+// its channel.
+func (wsc *MyWalletService) isLatest(state *channel.State) (bool, error) {
+	history, err := wsc.History(state.ID)
+	if err != nil || len(history) == 0 {
+		return false, err
+	}
+	latest, err := summarize(history[len(history)-1])
+	if err != nil {
+		return false, err
+	}
+	to, err := summarize(state)
+	if err != nil {
+		return false, err
+	}
+	return latest.equal(to), nil
+}
+
+// getState returns the latest known state of the channel with the given ID or
+// nil if the channel is unknown.
+func (wsc *MyWalletService) getState(id channel.ID) *channel.State {
+	wsc.stateMtx.Lock()
+	defer wsc.stateMtx.Unlock()
+	state, ok := wsc.states[id]
+	if !ok {
 		return nil
 	}
-	return wsc.state.Clone()
+	return state.Clone()
 }
 
-func toCKBState(protoState *protobuf.State) (*channel.State, error) {
+func toCKBState(protoState *protobuf.State, sudts []types.Script) (*channel.State, error) {
 	state := &channel.State{}
 	copy(state.ID[:], protoState.Id)
 	state.Version = protoState.Version
 	state.IsFinal = protoState.IsFinal
-	allocation, err := toCKBAllocation(protoState.Allocation)
+	allocation, err := toCKBAllocation(protoState.Allocation, sudts)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshalling state: %w", err)
 	}
@@ -219,20 +684,15 @@ func toCKBState(protoState *protobuf.State) (*channel.State, error) {
 	return state, nil
 }
 
-func toCKBAllocation(protoAlloc *protobuf.Allocation) (*channel.Allocation, error) {
+func toCKBAllocation(protoAlloc *protobuf.Allocation, sudts []types.Script) (*channel.Allocation, error) {
 	alloc := &channel.Allocation{}
 	alloc.Assets = make([]channel.Asset, len(protoAlloc.Assets))
 	for i := range protoAlloc.Assets {
-		// NOTE: We will assume the first asset will always be CKBytes.
-		if i == 0 {
-			alloc.Assets[i] = asset.NewCKBytesAsset()
-		} else {
-			alloc.Assets[i] = channel.NewAsset()
-		}
-		err := alloc.Assets[i].UnmarshalBinary(protoAlloc.Assets[i])
+		a, err := decodeAsset(protoAlloc.Assets[i], sudts)
 		if err != nil {
 			return nil, fmt.Errorf("%d'th asset: %w", i, err)
 		}
+		alloc.Assets[i] = a
 	}
 	alloc.Locked = make([]channel.SubAlloc, len(protoAlloc.Locked))
 	for i := range protoAlloc.Locked {
@@ -0,0 +1,53 @@
+package wallet_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"perun.network/channel-service/rpc/proto"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/go-perun/wire/protobuf"
+)
+
+func TestUnknownChannels(t *testing.T) {
+	ws, peer := newTestAccount(t), newTestAccount(t)
+	summary := func(state *channel.State) *stateSummary {
+		s, err := summarize(state)
+		require.NoError(t, err)
+		return s
+	}
+	notify := func(state *channel.State) bool {
+		protoState, err := protobuf.FromState(state)
+		require.NoError(t, err)
+		resp, err := ws.UpdateNotification(context.Background(), &proto.UpdateNotificationRequest{State: protoState})
+		require.NoError(t, err)
+		return resp.Accepted
+	}
+
+	// Initial states of channels which we neither proposed nor accepted are
+	// neither signed nor accepted.
+	initial := testState(channel.ID{1}, 0, 500)
+	require.ErrorContains(t, ws.verifySigning(summary(initial)), "not proposed or accepted")
+	require.False(t, notify(initial))
+
+	// The initial state of our own proposal is signed and its participants
+	// are recorded, but only for a single channel.
+	parts := []gpwallet.Address{ws.signer.Address(), peer.signer.Address()}
+	revoke, err := ws.AuthorizeProposal(parts, &testState(channel.ID{}, 0, 500).Allocation)
+	require.NoError(t, err)
+	defer revoke()
+	require.Error(t, ws.verifySigning(summary(testState(channel.ID{1}, 0, 400))), "balances differ")
+	require.NoError(t, ws.verifySigning(summary(initial)))
+	require.Equal(t, parts, ws.Participants(initial.ID))
+	require.Error(t, ws.verifySigning(summary(testState(channel.ID{2}, 0, 500))))
+	require.True(t, notify(initial))
+	require.Len(t, ws.Channels(), 1)
+
+	// The initial state of an accepted channel has to match the proposal.
+	ws.opening[channel.ID{3}] = summary(testState(channel.ID{3}, 0, 0))
+	require.ErrorContains(t, ws.verifySigning(summary(testState(channel.ID{3}, 0, 500))), "does not match")
+	require.False(t, notify(testState(channel.ID{3}, 0, 500)))
+	require.True(t, notify(testState(channel.ID{3}, 0, 0)))
+}
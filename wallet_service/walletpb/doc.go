@@ -0,0 +1,7 @@
+// Package walletpb contains the messages and the gRPC service of the API
+// which a wallet service offers to the client of an account, see
+// wallet_account.proto. The code is generated with protoc-gen-go and
+// protoc-gen-go-grpc.
+package walletpb
+
+//go:generate sh -c "protoc -I. -I$(go list -m -f '{{.Dir}}' perun.network/go-perun)/wire/protobuf --go_out=paths=source_relative:. --go-grpc_out=paths=source_relative:. wallet_account.proto"
@@ -0,0 +1,389 @@
+// This file describes the API of a wallet service for the client of an
+// account, which is offered next to the wallet service API of the channel
+// service.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: wallet_account.proto
+
+package walletpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	WalletAccount_Subscribe_FullMethodName         = "/perun_nervos_demo.WalletAccount/Subscribe"
+	WalletAccount_GetChannels_FullMethodName       = "/perun_nervos_demo.WalletAccount/GetChannels"
+	WalletAccount_GetHistory_FullMethodName        = "/perun_nervos_demo.WalletAccount/GetHistory"
+	WalletAccount_AuthorizeProposal_FullMethodName = "/perun_nervos_demo.WalletAccount/AuthorizeProposal"
+	WalletAccount_AuthorizeUpdate_FullMethodName   = "/perun_nervos_demo.WalletAccount/AuthorizeUpdate"
+	WalletAccount_Revoke_FullMethodName            = "/perun_nervos_demo.WalletAccount/Revoke"
+	WalletAccount_CloseChannel_FullMethodName      = "/perun_nervos_demo.WalletAccount/CloseChannel"
+)
+
+// WalletAccountClient is the client API for WalletAccount service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WalletAccountClient interface {
+	// Subscribe streams the events of the account. The stream starts with an
+	// update event for the latest state of every open channel. It ends with the
+	// status ResourceExhausted if the subscriber lags behind.
+	Subscribe(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (WalletAccount_SubscribeClient, error)
+	// GetChannels returns the latest states of the open channels.
+	GetChannels(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*ChannelsResponse, error)
+	// GetHistory returns all states of a channel on which both participants
+	// agreed, ordered by version.
+	GetHistory(ctx context.Context, in *ChannelRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	// AuthorizeProposal allows the wallet service to sign the initial state of
+	// a channel which the client proposes, until it is revoked.
+	AuthorizeProposal(ctx context.Context, in *AuthorizeProposalRequest, opts ...grpc.CallOption) (*Authorization, error)
+	// AuthorizeUpdate allows the wallet service to sign an update which the
+	// client proposes, even if it decreases our balance, until it is revoked.
+	AuthorizeUpdate(ctx context.Context, in *AuthorizeUpdateRequest, opts ...grpc.CallOption) (*Authorization, error)
+	// Revoke revokes an authorization.
+	Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*RevokeResponse, error)
+	// CloseChannel tells the wallet service that the client settled a channel.
+	// The channel is closed once the settlement is observed on-chain.
+	CloseChannel(ctx context.Context, in *ChannelRequest, opts ...grpc.CallOption) (*CloseChannelResponse, error)
+}
+
+type walletAccountClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWalletAccountClient(cc grpc.ClientConnInterface) WalletAccountClient {
+	return &walletAccountClient{cc}
+}
+
+func (c *walletAccountClient) Subscribe(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (WalletAccount_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WalletAccount_ServiceDesc.Streams[0], WalletAccount_Subscribe_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &walletAccountSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type WalletAccount_SubscribeClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type walletAccountSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *walletAccountSubscribeClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *walletAccountClient) GetChannels(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*ChannelsResponse, error) {
+	out := new(ChannelsResponse)
+	err := c.cc.Invoke(ctx, WalletAccount_GetChannels_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletAccountClient) GetHistory(ctx context.Context, in *ChannelRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, WalletAccount_GetHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletAccountClient) AuthorizeProposal(ctx context.Context, in *AuthorizeProposalRequest, opts ...grpc.CallOption) (*Authorization, error) {
+	out := new(Authorization)
+	err := c.cc.Invoke(ctx, WalletAccount_AuthorizeProposal_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletAccountClient) AuthorizeUpdate(ctx context.Context, in *AuthorizeUpdateRequest, opts ...grpc.CallOption) (*Authorization, error) {
+	out := new(Authorization)
+	err := c.cc.Invoke(ctx, WalletAccount_AuthorizeUpdate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletAccountClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*RevokeResponse, error) {
+	out := new(RevokeResponse)
+	err := c.cc.Invoke(ctx, WalletAccount_Revoke_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *walletAccountClient) CloseChannel(ctx context.Context, in *ChannelRequest, opts ...grpc.CallOption) (*CloseChannelResponse, error) {
+	out := new(CloseChannelResponse)
+	err := c.cc.Invoke(ctx, WalletAccount_CloseChannel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WalletAccountServer is the server API for WalletAccount service.
+// All implementations must embed UnimplementedWalletAccountServer
+// for forward compatibility
+type WalletAccountServer interface {
+	// Subscribe streams the events of the account. The stream starts with an
+	// update event for the latest state of every open channel. It ends with the
+	// status ResourceExhausted if the subscriber lags behind.
+	Subscribe(*AccountRequest, WalletAccount_SubscribeServer) error
+	// GetChannels returns the latest states of the open channels.
+	GetChannels(context.Context, *AccountRequest) (*ChannelsResponse, error)
+	// GetHistory returns all states of a channel on which both participants
+	// agreed, ordered by version.
+	GetHistory(context.Context, *ChannelRequest) (*HistoryResponse, error)
+	// AuthorizeProposal allows the wallet service to sign the initial state of
+	// a channel which the client proposes, until it is revoked.
+	AuthorizeProposal(context.Context, *AuthorizeProposalRequest) (*Authorization, error)
+	// AuthorizeUpdate allows the wallet service to sign an update which the
+	// client proposes, even if it decreases our balance, until it is revoked.
+	AuthorizeUpdate(context.Context, *AuthorizeUpdateRequest) (*Authorization, error)
+	// Revoke revokes an authorization.
+	Revoke(context.Context, *RevokeRequest) (*RevokeResponse, error)
+	// CloseChannel tells the wallet service that the client settled a channel.
+	// The channel is closed once the settlement is observed on-chain.
+	CloseChannel(context.Context, *ChannelRequest) (*CloseChannelResponse, error)
+	mustEmbedUnimplementedWalletAccountServer()
+}
+
+// UnimplementedWalletAccountServer must be embedded to have forward compatible implementations.
+type UnimplementedWalletAccountServer struct {
+}
+
+func (UnimplementedWalletAccountServer) Subscribe(*AccountRequest, WalletAccount_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedWalletAccountServer) GetChannels(context.Context, *AccountRequest) (*ChannelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChannels not implemented")
+}
+func (UnimplementedWalletAccountServer) GetHistory(context.Context, *ChannelRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedWalletAccountServer) AuthorizeProposal(context.Context, *AuthorizeProposalRequest) (*Authorization, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizeProposal not implemented")
+}
+func (UnimplementedWalletAccountServer) AuthorizeUpdate(context.Context, *AuthorizeUpdateRequest) (*Authorization, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizeUpdate not implemented")
+}
+func (UnimplementedWalletAccountServer) Revoke(context.Context, *RevokeRequest) (*RevokeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Revoke not implemented")
+}
+func (UnimplementedWalletAccountServer) CloseChannel(context.Context, *ChannelRequest) (*CloseChannelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseChannel not implemented")
+}
+func (UnimplementedWalletAccountServer) mustEmbedUnimplementedWalletAccountServer() {}
+
+// UnsafeWalletAccountServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WalletAccountServer will
+// result in compilation errors.
+type UnsafeWalletAccountServer interface {
+	mustEmbedUnimplementedWalletAccountServer()
+}
+
+func RegisterWalletAccountServer(s grpc.ServiceRegistrar, srv WalletAccountServer) {
+	s.RegisterService(&WalletAccount_ServiceDesc, srv)
+}
+
+func _WalletAccount_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AccountRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WalletAccountServer).Subscribe(m, &walletAccountSubscribeServer{stream})
+}
+
+type WalletAccount_SubscribeServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type walletAccountSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *walletAccountSubscribeServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _WalletAccount_GetChannels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAccountServer).GetChannels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletAccount_GetChannels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAccountServer).GetChannels(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletAccount_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChannelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAccountServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletAccount_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAccountServer).GetHistory(ctx, req.(*ChannelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletAccount_AuthorizeProposal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeProposalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAccountServer).AuthorizeProposal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletAccount_AuthorizeProposal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAccountServer).AuthorizeProposal(ctx, req.(*AuthorizeProposalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletAccount_AuthorizeUpdate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeUpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAccountServer).AuthorizeUpdate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletAccount_AuthorizeUpdate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAccountServer).AuthorizeUpdate(ctx, req.(*AuthorizeUpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletAccount_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAccountServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletAccount_Revoke_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAccountServer).Revoke(ctx, req.(*RevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WalletAccount_CloseChannel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChannelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WalletAccountServer).CloseChannel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WalletAccount_CloseChannel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WalletAccountServer).CloseChannel(ctx, req.(*ChannelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WalletAccount_ServiceDesc is the grpc.ServiceDesc for WalletAccount service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WalletAccount_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "perun_nervos_demo.WalletAccount",
+	HandlerType: (*WalletAccountServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetChannels",
+			Handler:    _WalletAccount_GetChannels_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _WalletAccount_GetHistory_Handler,
+		},
+		{
+			MethodName: "AuthorizeProposal",
+			Handler:    _WalletAccount_AuthorizeProposal_Handler,
+		},
+		{
+			MethodName: "AuthorizeUpdate",
+			Handler:    _WalletAccount_AuthorizeUpdate_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _WalletAccount_Revoke_Handler,
+		},
+		{
+			MethodName: "CloseChannel",
+			Handler:    _WalletAccount_CloseChannel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _WalletAccount_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "wallet_account.proto",
+}
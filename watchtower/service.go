@@ -0,0 +1,82 @@
+package watchtower
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire/protobuf"
+)
+
+// The watchtower service reuses the protobuf messages of go-perun, so that no
+// additional code generation is needed:
+//
+//	service Watchtower {
+//	  rpc Watch(perunwire.SignedState) returns (google.protobuf.Empty);
+//	}
+const (
+	serviceName = "perun_nervos_demo.Watchtower"
+	watchMethod = "/" + serviceName + "/Watch"
+)
+
+// service is the server API of the watchtower service.
+type service interface {
+	Watch(*channel.SignedState) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Watch",
+		Handler:    watchHandler,
+	}},
+}
+
+// RegisterServer registers the watchtower w as service at s.
+func RegisterServer(s *grpc.Server, w *Watchtower) {
+	s.RegisterService(&serviceDesc, w)
+}
+
+func watchHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(protobuf.SignedState)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handle := func(ctx context.Context, req interface{}) (interface{}, error) {
+		signed, err := protobuf.ToSignedState(req.(*protobuf.SignedState))
+		if err != nil {
+			return nil, fmt.Errorf("watch: decoding signed state: %w", err)
+		}
+		if err := srv.(service).Watch(&signed); err != nil {
+			return nil, fmt.Errorf("watch: %w", err)
+		}
+		return &emptypb.Empty{}, nil
+	}
+	if interceptor == nil {
+		return handle(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: watchMethod}
+	return interceptor(ctx, req, info, handle)
+}
+
+// Client is the client of the watchtower service.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient creates a client of the watchtower service served at cc.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// Watch sends the signed state to the watchtower.
+func (c *Client) Watch(ctx context.Context, signed *channel.SignedState) error {
+	req, err := protobuf.FromSignedState(signed)
+	if err != nil {
+		return fmt.Errorf("converting signed state to protobuf: %w", err)
+	}
+	return c.cc.Invoke(ctx, watchMethod, req, new(emptypb.Empty))
+}
@@ -0,0 +1,89 @@
+package watchtower
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wire/protobuf"
+	"polycry.pt/poly-go/sortedkv"
+)
+
+const (
+	// statePrefix is the key prefix of the latest signed channel states.
+	statePrefix = "state:"
+)
+
+// Store persists the latest signed state of every watched channel.
+type Store struct {
+	db sortedkv.Database
+}
+
+// NewStore creates a store which keeps its data in db.
+func NewStore(db sortedkv.Database) *Store {
+	return &Store{db: db}
+}
+
+// Get returns the latest signed state of the channel with the given ID or nil
+// if the channel is not watched.
+func (s *Store) Get(id channel.ID) (*channel.SignedState, error) {
+	key := statePrefix + string(id[:])
+	// The backends report missing keys differently, so we check first.
+	if ok, err := s.db.Has(key); err != nil || !ok {
+		return nil, err
+	}
+	b, err := s.db.GetBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSignedState(b)
+}
+
+// Put stores the signed state as the latest state of its channel.
+func (s *Store) Put(signed *channel.SignedState) error {
+	b, err := encodeSignedState(signed)
+	if err != nil {
+		return err
+	}
+	return s.db.PutBytes(statePrefix+string(signed.State.ID[:]), b)
+}
+
+// All returns the latest signed states of all watched channels.
+func (s *Store) All() ([]*channel.SignedState, error) {
+	it := s.db.NewIteratorWithPrefix(statePrefix)
+	defer it.Close()
+	var states []*channel.SignedState
+	for it.Next() {
+		signed, err := decodeSignedState(it.ValueBytes())
+		if err != nil {
+			return nil, fmt.Errorf("key %x: %w", it.Key(), err)
+		}
+		states = append(states, signed)
+	}
+	return states, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func encodeSignedState(signed *channel.SignedState) ([]byte, error) {
+	protoSigned, err := protobuf.FromSignedState(signed)
+	if err != nil {
+		return nil, fmt.Errorf("converting signed state to protobuf: %w", err)
+	}
+	return proto.Marshal(protoSigned)
+}
+
+func decodeSignedState(b []byte) (*channel.SignedState, error) {
+	var protoSigned protobuf.SignedState
+	if err := proto.Unmarshal(b, &protoSigned); err != nil {
+		return nil, fmt.Errorf("unmarshalling signed state: %w", err)
+	}
+	signed, err := protobuf.ToSignedState(&protoSigned)
+	if err != nil {
+		return nil, fmt.Errorf("converting signed state from protobuf: %w", err)
+	}
+	return &signed, nil
+}
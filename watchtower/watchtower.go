@@ -0,0 +1,190 @@
+// Package watchtower implements a watchtower for Perun channels on CKB. The
+// watchtower receives the latest signed states of channels and watches the CKB
+// node for channel cells (PCTS) in which an older state is registered. It
+// answers such a stale dispute with the newer state, so that participants
+// cannot be cheated while they are offline.
+package watchtower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"perun.network/go-perun/channel"
+	"perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/backend"
+	_ "perun.network/perun-ckb-backend/channel" // Registers the CKB channel backend.
+	"perun.network/perun-ckb-backend/encoding"
+	molecule2 "perun.network/perun-ckb-backend/encoding/molecule"
+)
+
+// DefaultPollInterval is the default interval in which the watchtower checks
+// the chain.
+const DefaultPollInterval = 2 * time.Second
+
+// Chain is the part of the CKB RPC client (rpc.Client) which the watchtower
+// uses to watch the chain.
+type Chain interface {
+	GetCells(ctx context.Context, searchKey *indexer.SearchKey, order indexer.SearchOrder, limit uint64, afterCursor string) (*indexer.LiveCells, error)
+}
+
+// Disputer answers a dispute by registering a newer state. It is implemented
+// by the CKB client of the perun-ckb-backend.
+type Disputer interface {
+	Dispute(ctx context.Context, id channel.ID, state *channel.State, sigs []wallet.Sig, params *channel.Params) error
+}
+
+// Watchtower watches the chain for stale disputes of the channels whose latest
+// states it received.
+type Watchtower struct {
+	mtx      sync.Mutex // Serializes updates of the store.
+	store    *Store
+	chain    Chain
+	disputer Disputer
+	pcts     *types.Script // Prefix of the type scripts of all channel cells.
+	interval time.Duration
+}
+
+// New creates a watchtower which keeps the channel states in store, watches
+// chain for channel cells of the given deployment and answers stale disputes
+// with disputer.
+func New(store *Store, chain Chain, disputer Disputer, deployment backend.Deployment, interval time.Duration) *Watchtower {
+	return &Watchtower{
+		store:    store,
+		chain:    chain,
+		disputer: disputer,
+		pcts: &types.Script{
+			CodeHash: deployment.PCTSCodeHash,
+			HashType: deployment.PCTSHashType,
+			Args:     []byte{},
+		},
+		interval: interval,
+	}
+}
+
+// Watch makes the watchtower watch the channel of the given signed state. The
+// state must be signed by all participants and replaces the stored state of
+// the channel if its version is higher.
+func (w *Watchtower) Watch(signed *channel.SignedState) error {
+	if err := verifySignedState(signed); err != nil {
+		return err
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	old, err := w.store.Get(signed.State.ID)
+	if err != nil {
+		return fmt.Errorf("reading stored state: %w", err)
+	}
+	if old != nil && old.State.Version >= signed.State.Version {
+		return nil
+	}
+	if err := w.store.Put(signed); err != nil {
+		return fmt.Errorf("storing state: %w", err)
+	}
+	log.Printf("watchtower: watching channel %x at version %d", signed.State.ID, signed.State.Version)
+	return nil
+}
+
+// Run checks the chain periodically until ctx is done.
+func (w *Watchtower) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Check(ctx); err != nil {
+			log.Printf("watchtower: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check looks for channel cells in which an older state than the stored one
+// is registered and answers each of these disputes with the stored state. The
+// disputes of different channels are answered independently, the errors are
+// joined.
+func (w *Watchtower) Check(ctx context.Context) error {
+	searchKey := &indexer.SearchKey{
+		Script:           w.pcts,
+		ScriptType:       types.ScriptTypeType,
+		ScriptSearchMode: types.ScriptSearchModePrefix,
+		Filter:           nil,
+		WithData:         true,
+	}
+	cells, err := w.chain.GetCells(ctx, searchKey, indexer.SearchOrderDesc, math.MaxUint32, "")
+	if err != nil {
+		return fmt.Errorf("getting channel cells: %w", err)
+	}
+
+	var errs []error
+	for _, cell := range cells.Objects {
+		if cell.Output == nil || cell.Output.Type == nil ||
+			cell.Output.Type.CodeHash != w.pcts.CodeHash || cell.Output.Type.HashType != w.pcts.HashType {
+			continue
+		}
+		status, err := molecule.ChannelStatusFromSlice(cell.OutputData, false)
+		if err != nil {
+			continue
+		}
+		if err := w.checkChannel(ctx, status); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkChannel answers the dispute of the channel with the given on-chain
+// status if an older state than the stored one is registered.
+func (w *Watchtower) checkChannel(ctx context.Context, status *molecule.ChannelStatus) error {
+	if !encoding.ToBool(*status.Disputed()) {
+		return nil
+	}
+	id := types.UnpackHash(status.State().ChannelId())
+	signed, err := w.store.Get(id)
+	if err != nil {
+		return fmt.Errorf("channel %x: reading stored state: %w", id, err)
+	}
+	registered := molecule2.UnpackUint64(status.State().Version())
+	if signed == nil || signed.State.Version <= registered {
+		return nil
+	}
+
+	log.Printf("watchtower: channel %x: answering dispute of version %d with version %d", id, registered, signed.State.Version)
+	if err := w.disputer.Dispute(ctx, id, signed.State, signed.Sigs, signed.Params); err != nil {
+		return fmt.Errorf("channel %x: disputing: %w", id, err)
+	}
+	return nil
+}
+
+// verifySignedState checks that the state belongs to the parameters and is
+// signed by all participants.
+func verifySignedState(signed *channel.SignedState) error {
+	if signed.Params == nil || signed.State == nil {
+		return errors.New("missing parameters or state")
+	}
+	if signed.State.ID != signed.Params.ID() {
+		return errors.New("state does not belong to the parameters")
+	}
+	if len(signed.Sigs) != len(signed.Params.Parts) {
+		return fmt.Errorf("expected %d signatures, got %d", len(signed.Params.Parts), len(signed.Sigs))
+	}
+	for i, part := range signed.Params.Parts {
+		ok, err := channel.Verify(part, signed.State, signed.Sigs[i])
+		if err != nil {
+			return fmt.Errorf("verifying signature %d: %w", i, err)
+		}
+		if !ok {
+			return fmt.Errorf("invalid signature of participant %d", i)
+		}
+	}
+	return nil
+}
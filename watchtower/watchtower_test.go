@@ -0,0 +1,175 @@
+package watchtower_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/indexer"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types"
+	"github.com/nervosnetwork/ckb-sdk-go/v2/types/molecule"
+	"github.com/stretchr/testify/require"
+	"perun.network/go-perun/channel"
+	gpwallet "perun.network/go-perun/wallet"
+	"perun.network/perun-ckb-backend/backend"
+	"perun.network/perun-ckb-backend/channel/asset"
+	"perun.network/perun-ckb-backend/encoding"
+	"perun.network/perun-ckb-backend/wallet"
+	"perun.network/perun-nervos-demo/watchtower"
+	"polycry.pt/poly-go/sortedkv/memorydb"
+)
+
+var deployment = backend.Deployment{
+	PCTSCodeHash: types.Hash{1},
+	PCTSHashType: types.HashTypeData1,
+}
+
+func TestWatchtower_Watch(t *testing.T) {
+	ch := newTestChannel(t)
+	store := watchtower.NewStore(memorydb.NewDatabase())
+	w := watchtower.New(store, &mockChain{}, &mockDisputer{}, deployment, watchtower.DefaultPollInterval)
+
+	require.NoError(t, w.Watch(ch.signedState(t, 2)))
+	// Older states do not replace the stored one.
+	require.NoError(t, w.Watch(ch.signedState(t, 1)))
+	stored, err := store.Get(ch.params.ID())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), stored.State.Version)
+	require.NoError(t, stored.State.Equal(ch.state(2)))
+
+	// States without valid signatures of all participants are rejected.
+	invalid := ch.signedState(t, 3)
+	invalid.Sigs[1] = invalid.Sigs[0]
+	require.Error(t, w.Watch(invalid))
+	invalid.Sigs = invalid.Sigs[:1]
+	require.Error(t, w.Watch(invalid))
+}
+
+func TestWatchtower_Check(t *testing.T) {
+	ch := newTestChannel(t)
+	chain := &mockChain{}
+	disputer := &mockDisputer{}
+	w := watchtower.New(watchtower.NewStore(memorydb.NewDatabase()), chain, disputer, deployment, watchtower.DefaultPollInterval)
+	require.NoError(t, w.Watch(ch.signedState(t, 2)))
+
+	// Nothing to do if the channel is not disputed or the registered state is
+	// not older than the stored one.
+	chain.cells = []*indexer.LiveCell{ch.cell(t, 1, false)}
+	require.NoError(t, w.Check(context.Background()))
+	chain.cells = []*indexer.LiveCell{ch.cell(t, 2, true)}
+	require.NoError(t, w.Check(context.Background()))
+	require.Empty(t, disputer.disputed)
+
+	// Other channels and cells of other scripts are ignored.
+	other := newTestChannel(t)
+	foreign := ch.cell(t, 1, true)
+	foreign.Output.Type = &types.Script{CodeHash: types.Hash{2}, HashType: deployment.PCTSHashType}
+	chain.cells = []*indexer.LiveCell{other.cell(t, 1, true), foreign}
+	require.NoError(t, w.Check(context.Background()))
+	require.Empty(t, disputer.disputed)
+
+	// A stale dispute is answered with the stored state.
+	chain.cells = []*indexer.LiveCell{ch.cell(t, 1, true)}
+	require.NoError(t, w.Check(context.Background()))
+	require.Len(t, disputer.disputed, 1)
+	require.Equal(t, ch.params.ID(), disputer.disputed[0].State.ID)
+	require.Equal(t, uint64(2), disputer.disputed[0].State.Version)
+	require.Len(t, disputer.disputed[0].Sigs, 2)
+	require.Equal(t, deployment.PCTSCodeHash, chain.searchKey.Script.CodeHash)
+	require.Equal(t, types.ScriptSearchModePrefix, chain.searchKey.ScriptSearchMode)
+}
+
+func TestStore_Persistence(t *testing.T) {
+	ch := newTestChannel(t)
+	db := memorydb.NewDatabase()
+	require.NoError(t, watchtower.NewStore(db).Put(ch.signedState(t, 5)))
+
+	all, err := watchtower.NewStore(db).All()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Equal(t, uint64(5), all[0].State.Version)
+	require.Equal(t, ch.params.ID(), all[0].Params.ID())
+}
+
+// testChannel is a two-party channel with CKBytes only.
+type testChannel struct {
+	accs   []*wallet.Account
+	params *channel.Params
+}
+
+func newTestChannel(t *testing.T) *testChannel {
+	ch := &testChannel{}
+	parts := make([]gpwallet.Address, 2)
+	for i := range parts {
+		key, err := secp256k1.GeneratePrivateKey()
+		require.NoError(t, err)
+		acc := wallet.NewAccountFromPrivateKey(key)
+		ch.accs = append(ch.accs, acc)
+		parts[i] = acc.Address()
+	}
+	ch.params = channel.NewParamsUnsafe(10, parts, channel.NoApp(), big.NewInt(42), true, false)
+	return ch
+}
+
+func (ch *testChannel) state(version uint64) *channel.State {
+	ckb := asset.NewCKBytesAsset()
+	alloc := channel.NewAllocation(2, ckb)
+	alloc.SetAssetBalances(ckb, []channel.Bal{big.NewInt(5_000_000_000), big.NewInt(5_000_000_000)})
+	return &channel.State{
+		ID:         ch.params.ID(),
+		Version:    version,
+		App:        channel.NoApp(),
+		Allocation: *alloc,
+		Data:       channel.NoData(),
+	}
+}
+
+func (ch *testChannel) signedState(t *testing.T, version uint64) *channel.SignedState {
+	state := ch.state(version)
+	sigs := make([]gpwallet.Sig, len(ch.accs))
+	for i, acc := range ch.accs {
+		sig, err := channel.Sign(acc, state)
+		require.NoError(t, err)
+		sigs[i] = sig
+	}
+	return &channel.SignedState{Params: ch.params, State: state, Sigs: sigs}
+}
+
+// cell returns the channel cell in which the given version is registered.
+func (ch *testChannel) cell(t *testing.T, version uint64, disputed bool) *indexer.LiveCell {
+	packed, err := encoding.PackChannelState(ch.state(version))
+	require.NoError(t, err)
+	status := molecule.NewChannelStatusBuilder().
+		State(packed).
+		Funded(encoding.True).
+		Disputed(encoding.FromBool(disputed)).
+		Build()
+	return &indexer.LiveCell{
+		Output: &types.CellOutput{
+			Type: &types.Script{CodeHash: deployment.PCTSCodeHash, HashType: deployment.PCTSHashType, Args: []byte{byte(version)}},
+		},
+		OutputData: status.AsSlice(),
+	}
+}
+
+// mockChain returns a fixed set of channel cells.
+type mockChain struct {
+	cells     []*indexer.LiveCell
+	searchKey *indexer.SearchKey
+}
+
+func (c *mockChain) GetCells(_ context.Context, searchKey *indexer.SearchKey, _ indexer.SearchOrder, _ uint64, _ string) (*indexer.LiveCells, error) {
+	c.searchKey = searchKey
+	return &indexer.LiveCells{Objects: c.cells}, nil
+}
+
+// mockDisputer records the disputes.
+type mockDisputer struct {
+	disputed []channel.SignedState
+}
+
+func (d *mockDisputer) Dispute(_ context.Context, _ channel.ID, state *channel.State, sigs []gpwallet.Sig, params *channel.Params) error {
+	d.disputed = append(d.disputed, channel.SignedState{Params: params, State: state, Sigs: sigs})
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nervosnetwork/ckb-sdk-go/v2/rpc"
+	"google.golang.org/grpc"
+	"perun.network/perun-ckb-backend/backend"
+	"perun.network/perun-ckb-backend/client"
+	"perun.network/perun-ckb-backend/wallet"
+	"perun.network/perun-ckb-backend/wallet/address"
+	"perun.network/perun-nervos-demo/config"
+	"perun.network/perun-nervos-demo/deployment"
+	"perun.network/perun-nervos-demo/keystore"
+	"perun.network/perun-nervos-demo/mtls"
+	"perun.network/perun-nervos-demo/watchtower"
+	"polycry.pt/poly-go/sortedkv/leveldb"
+)
+
+const (
+	// configPath is the default location of the demo configuration.
+	configPath = "../config.yaml"
+)
+
+// SetLogFile sets the log file for the watchtower service.
+func SetLogFile(path string) {
+	logFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		log.Fatalf("error opening file: %v", err)
+	}
+	log.SetOutput(logFile)
+}
+
+// Start watchtower GRPC server.
+func main() {
+	SetLogFile("watchtower_service.log")
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+	if cfg.Watchtower == nil {
+		log.Fatalf("no watchtower configured")
+	}
+	network, err := cfg.CKBNetwork()
+	if err != nil {
+		log.Fatalf("error getting network: %v", err)
+	}
+
+	sudtOwnerLockArg, err := deployment.GetSUDTOwnerLockArg(cfg.SUDTOwnerLockArgFile())
+	if err != nil {
+		log.Fatalf("error getting SUDT owner lock arg: %v", err)
+	}
+	d, _, err := deployment.GetDeployment(cfg.MigrationDir(), cfg.SystemScriptsDir(), sudtOwnerLockArg)
+	if err != nil {
+		log.Fatalf("error getting deployment: %v", err)
+	}
+
+	// The watchtower pays the fees of the disputes with its own account.
+	key, err := keystore.Unlock(cfg.Watchtower.KeyFile, cfg.Watchtower.KeyFormat, keystore.PassphraseSource{
+		Env:    config.PassphraseEnv("watchtower"),
+		File:   cfg.Watchtower.PassphraseFile,
+		Prompt: "Passphrase of the watchtower's key: ",
+	})
+	if err != nil {
+		log.Fatalf("error getting the watchtower's private key: %v", err)
+	}
+	acc := wallet.NewAccountFromPrivateKey(key)
+	ckbAddr := address.AsParticipant(acc.Address()).ToCKBAddress(network)
+	signer := backend.NewSignerInstance(ckbAddr, *key, network)
+
+	rpcClient, err := rpc.Dial(cfg.NodeURL)
+	if err != nil {
+		log.Fatalf("error dialing CKB node: %v", err)
+	}
+	disputer, err := client.NewClient(rpcClient, signer, d)
+	if err != nil {
+		log.Fatalf("error creating CKB client: %v", err)
+	}
+
+	db, err := leveldb.LoadDatabase(cfg.Watchtower.DBDir)
+	if err != nil {
+		log.Fatalf("loading database: %v", err)
+	}
+	store := watchtower.NewStore(db)
+	defer store.Close()
+	wt := watchtower.New(store, rpcClient, disputer, d, watchtower.DefaultPollInterval)
+
+	lis, err := net.Listen("tcp", cfg.Watchtower.Address)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	// With mutual TLS, the watchtower serves every holder of a certificate of
+	// the CA. It only keeps states which all participants signed anyway.
+	var opts []grpc.ServerOption
+	if cfg.TLS != nil {
+		creds, err := mtls.ServerCredentials(mtls.DirFiles(cfg.TLS.CertDir, mtls.Watchtower))
+		if err != nil {
+			log.Fatalf("loading watchtower certificate: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(opts...)
+	watchtower.RegisterServer(s, wt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		fmt.Printf("Starting Watchtower Server at %s \n", cfg.Watchtower.Address)
+		if err := s.Serve(lis); err != nil {
+			log.Fatalf("serving watchtower: %v", err)
+		}
+	}()
+	go func() {
+		_ = wt.Run(ctx)
+	}()
+
+	// Signal handling for graceful shutdown
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+	fmt.Println("Shutting down watchtower...")
+	cancel()
+	s.Stop()
+	fmt.Println("Watchtower stopped.")
+}